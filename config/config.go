@@ -0,0 +1,339 @@
+// Package config собирает настройки приложения в одну проверенную структуру. Значение каждой настройки
+// берётся из первого источника, где оно задано: флаги командной строки, переменные окружения, файл настроек
+// (по умолчанию .env), значения по умолчанию. Вместо значения можно указать файл, из которого его прочитать,
+// в переменной с суффиксом _FILE, например SESSION_SECRET_FILE=/run/secrets/session_secret.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"NotesWebApp/mail"
+	"NotesWebApp/sso"
+	"NotesWebApp/storage"
+	"NotesWebApp/validation"
+)
+
+// DefaultFile — файл настроек, который читается, если он есть и другой файл не указан.
+const DefaultFile = ".env"
+
+// Источники значений настроек.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+const (
+	fileSuffix = "_FILE"
+	redacted   = "[redacted]"
+)
+
+// Config — действующие настройки приложения.
+type Config struct {
+	Server   Server
+	Database Database
+	Session  Session
+
+	// AppURL — внешний адрес приложения для ссылок в письмах и публичных ссылок, без завершающего "/".
+	// Если APP_URL не задан, это локальный адрес сервера.
+	AppURL     string
+	AdminEmail string
+	// EmailVerification — что запрещено до подтверждения адреса: optional, notes или login.
+	EmailVerification string
+	PasswordMinLength int
+	TrashRetention    time.Duration
+
+	Attachments Attachments
+	SMTP        mail.SMTPConfig
+	// OIDC.Issuer пуст, если вход через OpenID Connect выключен.
+	OIDC sso.Config
+
+	// File — прочитанный файл настроек; пуст, если файла нет.
+	File string
+	// PrintConfig — вывести действующие настройки и завершить работу (флаг -print-config).
+	PrintConfig bool
+
+	settings []setting
+}
+
+// Server — настройки HTTP-сервера.
+type Server struct {
+	Addr         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+}
+
+// Database — подключение к PostgreSQL: DATABASE_URL или его части DB_*.
+type Database struct {
+	URL      string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+}
+
+// Session — настройки cookie сессии.
+type Session struct {
+	Name   string
+	Secret string
+	MaxAge time.Duration
+	// Secure — отправлять cookie только по HTTPS.
+	Secure bool
+}
+
+// Attachments — хранилище вложений: local (каталог Dir) или s3.
+type Attachments struct {
+	Storage string
+	Dir     string
+	S3      storage.S3Config
+}
+
+// setting — значение настройки для вывода в Print; секреты уже скрыты.
+type setting struct {
+	key    string
+	value  string
+	source string
+}
+
+// flagKeys — флаги командной строки и настройки, которые они задают.
+var flagKeys = []struct{ name, key string }{
+	{"listen", "LISTEN_ADDR"},
+	{"app-url", "APP_URL"},
+	{"attachment-dir", "ATTACHMENT_DIR"},
+}
+
+// DSN возвращает строку подключения к базе: DATABASE_URL или адрес, собранный из DB_*.
+func (d Database) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:   "/" + d.Name,
+	}
+	if d.Password == "" {
+		dsn.User = url.User(d.User)
+	}
+	if d.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {d.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+// Load читает настройки из аргументов командной строки args, переменных окружения (lookupEnv,
+// обычно os.LookupEnv) и файла настроек и проверяет их. Все найденные ошибки возвращаются вместе.
+// Для -h возвращается flag.ErrHelp.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := &Config{}
+
+	flags := flag.NewFlagSet("notes", flag.ContinueOnError)
+	file := flags.String("config", "", "configuration file (default "+DefaultFile+", if it exists)")
+	flags.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	for _, f := range flagKeys {
+		flags.String(f.name, "", "overrides "+f.key)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		for _, fk := range flagKeys {
+			if fk.name == f.Name {
+				set[fk.key] = f.Value.String()
+			}
+		}
+	})
+
+	if *file == "" {
+		*file, _ = lookupEnv("CONFIG_FILE")
+	}
+	fileValues, err := readFile(*file)
+	if err != nil {
+		return nil, err
+	}
+	if fileValues != nil {
+		cfg.File = *file
+		if cfg.File == "" {
+			cfg.File = DefaultFile
+		}
+	}
+
+	l := &loader{layers: []layer{
+		{SourceFlag, lookupMap(set)},
+		{SourceEnv, lookupEnv},
+		{SourceFile, lookupMap(fileValues)},
+	}}
+	l.read(cfg)
+	l.validate(cfg)
+	if len(l.errs) > 0 {
+		return nil, errors.Join(l.errs...)
+	}
+
+	cfg.settings = l.settings
+	return cfg, nil
+}
+
+// readFile читает файл настроек в формате .env. Если путь не указан, читается DefaultFile,
+// а его отсутствие не считается ошибкой.
+func readFile(path string) (map[string]string, error) {
+	optional := path == ""
+	if optional {
+		path = DefaultFile
+	}
+
+	values, err := godotenv.Read(path)
+	if optional && errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Print выводит действующие настройки с источником каждого значения. Секреты скрыты,
+// а из адреса базы удаляется пароль.
+func (c *Config) Print(w io.Writer) error {
+	for _, s := range c.settings {
+		if _, err := fmt.Fprintf(w, "%s=%s (%s)\n", s.key, s.value, s.source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *loader) read(cfg *Config) {
+	cfg.Server = Server{
+		Addr:         l.string("LISTEN_ADDR", ":8080"),
+		ReadTimeout:  l.duration("HTTP_READ_TIMEOUT", time.Minute), // с запасом на загрузку и скачивание вложений
+		WriteTimeout: l.duration("HTTP_WRITE_TIMEOUT", time.Minute),
+		IdleTimeout:  l.duration("HTTP_IDLE_TIMEOUT", 30*time.Second),
+	}
+
+	cfg.Database = Database{
+		URL:      l.databaseURL("DATABASE_URL"),
+		Host:     l.string("DB_HOST", ""),
+		Port:     l.int("DB_PORT", 5432),
+		User:     l.string("DB_USER", ""),
+		Password: l.secret("DB_PASSWORD"),
+		Name:     l.string("DB_NAME", ""),
+		SSLMode:  l.string("DB_SSLMODE", ""),
+	}
+
+	cfg.Session = Session{
+		Name:   l.string("SESSION_NAME", "notes_session"),
+		Secret: l.secret("SESSION_SECRET"),
+		MaxAge: l.duration("SESSION_MAX_AGE", 30*24*time.Hour),
+		Secure: l.bool("SESSION_SECURE", false),
+	}
+
+	cfg.AppURL = strings.TrimRight(l.string("APP_URL", ""), "/")
+	cfg.AdminEmail = l.string("ADMIN_EMAIL", "")
+	cfg.EmailVerification = l.string("EMAIL_VERIFICATION", "optional")
+	cfg.PasswordMinLength = l.int("PASSWORD_MIN_LENGTH", validation.DefaultMinPasswordLength)
+	cfg.TrashRetention = time.Duration(l.int("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour
+
+	cfg.Attachments = Attachments{
+		Storage: l.string("ATTACHMENT_STORAGE", "local"),
+		Dir:     l.string("ATTACHMENT_DIR", "uploads"),
+		S3: storage.S3Config{
+			Endpoint:  l.string("S3_ENDPOINT", ""),
+			Region:    l.string("S3_REGION", ""),
+			Bucket:    l.string("S3_BUCKET", ""),
+			AccessKey: l.string("S3_ACCESS_KEY", ""),
+			SecretKey: l.secret("S3_SECRET_KEY"),
+		},
+	}
+
+	// Порт 0 означает порт по умолчанию пакета mail.
+	cfg.SMTP = mail.SMTPConfig{
+		Host:     l.string("SMTP_HOST", ""),
+		Port:     l.int("SMTP_PORT", 0),
+		Username: l.string("SMTP_USERNAME", ""),
+		Password: l.secret("SMTP_PASSWORD"),
+		From:     l.string("MAIL_FROM", ""),
+	}
+
+	cfg.OIDC = sso.Config{
+		Issuer:       l.string("OIDC_ISSUER", ""),
+		ClientID:     l.string("OIDC_CLIENT_ID", ""),
+		ClientSecret: l.secret("OIDC_CLIENT_SECRET"),
+		RedirectURL:  l.string("OIDC_REDIRECT_URL", ""),
+		Name:         l.string("OIDC_PROVIDER_NAME", "SSO"),
+	}
+}
+
+// validate проверяет связанные настройки и заполняет значения, которые выводятся из других.
+func (l *loader) validate(cfg *Config) {
+	db := cfg.Database
+	if db.URL == "" && (db.Host == "" || db.User == "" || db.Name == "") {
+		l.fail(errors.New("DATABASE_URL or DB_HOST, DB_USER and DB_NAME must be set"))
+	}
+
+	if cfg.Session.Secret == "" {
+		l.fail(errors.New("SESSION_SECRET must be set"))
+	}
+
+	if cfg.AppURL != "" {
+		u, err := url.Parse(cfg.AppURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.fail(errors.New("APP_URL must be an absolute http or https URL"))
+		}
+	}
+
+	switch cfg.EmailVerification {
+	case "optional", "notes", "login":
+	default:
+		l.fail(fmt.Errorf("unknown EMAIL_VERIFICATION %q, expected optional, notes or login", cfg.EmailVerification))
+	}
+
+	switch cfg.Attachments.Storage {
+	case "local", "s3":
+	default:
+		l.fail(fmt.Errorf("unknown ATTACHMENT_STORAGE %q, expected local or s3", cfg.Attachments.Storage))
+	}
+
+	if cfg.OIDC.Issuer != "" && cfg.OIDC.RedirectURL == "" {
+		if cfg.AppURL == "" {
+			l.fail(errors.New("OIDC_REDIRECT_URL or APP_URL must be set to use OIDC_ISSUER"))
+		}
+		cfg.OIDC.RedirectURL = cfg.AppURL + "/login/oidc/callback"
+	}
+
+	// Ссылки в письмах и публичные ссылки строятся только от настроенного адреса, а не от заголовка Host
+	// запроса, который может подделать клиент. Без APP_URL подходит только локальный адрес.
+	if cfg.AppURL == "" {
+		cfg.AppURL = localURL(cfg.Server.Addr)
+	}
+}
+
+// localURL возвращает адрес сервера, слушающего addr, на этой машине.
+func localURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://localhost"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// layer — источник значений настроек.
+type layer struct {
+	source string
+	lookup func(key string) (string, bool)
+}
+
+// loader читает настройки из источников по убыванию приоритета, запоминает их для Print
+// и накапливает ошибки, чтобы сообщить обо всех неверных значениях сразу.
+type loader struct {
+	layers   []layer
+	settings []setting
+	errs     []error
+}
+
+func lookupMap(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func (l *loader) fail(err error) {
+	l.errs = append(l.errs, err)
+}
+
+// lookup возвращает значение key и его источник. Пустое значение считается незаданным. Если в источнике
+// задан key_FILE, значением становится содержимое этого файла без завершающего перевода строки.
+func (l *loader) lookup(key string) (string, string, bool) {
+	for _, src := range l.layers {
+		value, ok := src.lookup(key)
+		ok = ok && value != ""
+		path, fromFile := src.lookup(key + fileSuffix)
+		fromFile = fromFile && path != ""
+
+		switch {
+		case ok && fromFile:
+			l.fail(fmt.Errorf("%s and %s%s must not both be set", key, key, fileSuffix))
+			return "", src.source, false
+		case fromFile:
+			data, err := os.ReadFile(path) //nolint:gosec // путь к файлу задаёт администратор
+			if err != nil {
+				l.fail(fmt.Errorf("error reading %s%s: %w", key, fileSuffix, err))
+				return "", src.source, false
+			}
+			return strings.TrimRight(string(data), "\r\n"), src.source, true
+		case ok:
+			return value, src.source, true
+		}
+	}
+	return "", SourceDefault, false
+}
+
+func (l *loader) record(key, value, source string) {
+	l.settings = append(l.settings, setting{key: key, value: value, source: source})
+}
+
+func (l *loader) string(key, fallback string) string {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		value = fallback
+	}
+	l.record(key, value, source)
+	return value
+}
+
+// secret читает значение, которое не показывается в Print.
+func (l *loader) secret(key string) string {
+	value, source, _ := l.lookup(key)
+	shown := ""
+	if value != "" {
+		shown = redacted
+	}
+	l.record(key, shown, source)
+	return value
+}
+
+// databaseURL читает адрес базы; в Print он выводится без пароля.
+func (l *loader) databaseURL(key string) string {
+	value, source, _ := l.lookup(key)
+	shown := value
+	if value != "" {
+		shown = redacted
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+			shown = u.Redacted()
+		}
+	}
+	l.record(key, shown, source)
+	return value
+}
+
+// int читает положительное целое число.
+func (l *loader) int(key string, fallback int) int {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.Itoa(fallback), source)
+		return fallback
+	}
+
+	l.record(key, value, source)
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		l.fail(fmt.Errorf("%s must be a positive number, got %q", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+// duration читает положительную длительность в формате time.ParseDuration, например 30s или 12h.
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, fallback.String(), source)
+		return fallback
+	}
+
+	l.record(key, value, source)
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		l.fail(fmt.Errorf("%s must be a positive duration like 30s or 5m, got %q", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatBool(fallback), source)
+		return fallback
+	}
+
+	l.record(key, value, source)
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.fail(fmt.Errorf("%s must be true or false, got %q", key, value))
+		return fallback
+	}
+	return parsed
+}
@@ -2,20 +2,14 @@ package database
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq" // Регистрация драйвера PostgreSQL
 )
 
-func InitDB() (*sqlx.DB, error) {
-	err := godotenv.Load("./.env")
-	if err != nil {
-		return nil, fmt.Errorf("error loading .env file: %w", err)
-	}
-
-	db, err := sqlx.Connect("postgres", os.Getenv("DATABASE_URL"))
+// InitDB подключается к PostgreSQL по строке подключения dsn.
+func InitDB(dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+type AccountHandler struct {
+	DB *sqlx.DB
+}
+
+func NewAccountHandler(db *sqlx.DB) *AccountHandler {
+	return &AccountHandler{DB: db}
+}
+
+type accountSession struct {
+	models.Session
+	Current bool
+}
+
+type accountPage struct {
+	User              *models.User
+	Sessions          []accountSession
+	RecoveryCodesLeft int
+	Error             string
+}
+
+// currentSessionHash возвращает хеш токена сессии, с которой пришёл запрос, или пустую строку.
+func currentSessionHash(r *http.Request) string {
+	session, err := store.Get(r, sessionName)
+	if err != nil || session.ID == "" {
+		return ""
+	}
+	return models.HashToken(session.ID)
+}
+
+// currentSessionID возвращает идентификатор записи сессии, с которой пришёл запрос, или 0.
+func (ach *AccountHandler) currentSessionID(r *http.Request) int {
+	session, err := store.Get(r, sessionName)
+	if err != nil || session.ID == "" {
+		return 0
+	}
+	row, err := models.GetSessionByToken(ach.DB, session.ID)
+	if err != nil || row == nil {
+		return 0
+	}
+	return row.ID
+}
+
+// currentUser возвращает вошедшего пользователя или перенаправляет на страницу входа.
+func (ach *AccountHandler) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	user, err := models.GetUserByID(ach.DB, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	return user, true
+}
+
+// endCurrentSession удаляет cookie сессии текущего устройства.
+func endCurrentSession(w http.ResponseWriter, r *http.Request) error {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		return err
+	}
+	session.Values = make(map[interface{}]interface{})
+	session.Options.MaxAge = -1
+	return session.Save(r, w)
+}
+
+func (ach *AccountHandler) Account(w http.ResponseWriter, r *http.Request) {
+	user, ok := ach.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := models.GetSessionsByUser(ach.DB, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	current := currentSessionHash(r)
+	page := accountPage{
+		User:     user,
+		Sessions: make([]accountSession, 0, len(sessions)),
+		Error:    popFlash(w, r, accountFlashKey),
+	}
+	for i := range sessions {
+		page.Sessions = append(page.Sessions, accountSession{
+			Session: sessions[i],
+			Current: sessions[i].TokenHash == current,
+		})
+	}
+
+	if user.TwoFactorEnabled() {
+		page.RecoveryCodesLeft, err = models.CountRecoveryCodes(ach.DB, user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tmpl := template.Must(template.New("account.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/account.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing account.html:", err)
+		return
+	}
+}
+
+// RevokeSession завершает одну сессию пользователя. Если это сессия текущего устройства,
+// пользователь перенаправляется на страницу входа.
+func (ach *AccountHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	isCurrent := ach.currentSessionID(r) == id
+
+	deleted, err := models.DeleteSession(ach.DB, id, userID)
+	if err != nil {
+		log.Printf("Failed to revoke session %d: %v", id, err)
+		http.Error(w, "Failed to sign out the device", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("User %d revoked session %d", userID, id)
+
+	if isCurrent {
+		if err := endCurrentSession(w, r); err != nil {
+			log.Println("Can't clear session cookie:", err)
+		}
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// RevokeAllSessions завершает все сессии пользователя, включая текущую.
+func (ach *AccountHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	revoked, err := models.DeleteUserSessions(ach.DB, userID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of user %d: %v", userID, err)
+		http.Error(w, "Failed to sign out everywhere", http.StatusInternalServerError)
+		return
+	}
+
+	if err := endCurrentSession(w, r); err != nil {
+		log.Println("Can't clear session cookie:", err)
+	}
+
+	log.Printf("User %d signed out of %d sessions", userID, revoked)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
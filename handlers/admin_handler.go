@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/mail"
+	"NotesWebApp/models"
+)
+
+const (
+	adminPageSize     = 50
+	adminFailedLogins = 20
+	// adminStatsWindow — за какой период показываются неудачные попытки входа.
+	adminStatsWindow = 24 * time.Hour
+	adminNoticeKey   = "admin-notice"
+	adminErrorKey    = "admin-error"
+)
+
+// AdminHandler — консоль администратора: пользователи, их блокировка и удаление, сводка по приложению.
+type AdminHandler struct {
+	DB *sqlx.DB
+	// Auth отправляет письма со ссылкой для сброса пароля.
+	Auth *AuthHandler
+}
+
+func NewAdminHandler(db *sqlx.DB, auth *AuthHandler) *AdminHandler {
+	return &AdminHandler{DB: db, Auth: auth}
+}
+
+type adminPage struct {
+	Stats        models.SystemStats
+	Users        []models.UserSummary
+	FailedLogins []models.LoginAttempt
+	CurrentID    int
+	Search       string
+	// PrevURL и NextURL пусты, если соседней страницы нет.
+	PrevURL string
+	NextURL string
+	Notice  string
+	Error   string
+}
+
+// RequireAdmin пропускает к next только администраторов, вошедших через браузер.
+// Bearer-токены для консоли не подходят: они выдаются для работы с заметками.
+func (adh *AdminHandler) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if authMethod(r) != authMethodSession {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		user, err := models.GetUserByID(adh.DB, userID)
+		if err != nil {
+			log.Printf("Failed to get user %d: %v", userID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil || !user.IsAdmin() || user.IsDisabled() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (adh *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUserID(r)
+	page := adminPage{
+		CurrentID: userID,
+		Search:    strings.TrimSpace(r.URL.Query().Get("q")),
+		Notice:    popFlash(w, r, adminNoticeKey),
+		Error:     popFlash(w, r, adminErrorKey),
+	}
+	number, ok := pageNumber(r)
+	if !ok {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	page.Stats, err = models.GetSystemStats(adh.DB, time.Now().Add(-adminStatsWindow))
+	if err != nil {
+		log.Printf("Failed to get system stats: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Запрашиваем на одного пользователя больше, чтобы узнать, есть ли следующая страница.
+	users, err := models.ListUsers(adh.DB, page.Search, adminPageSize+1, (number-1)*adminPageSize)
+	if err != nil {
+		log.Printf("Failed to list users: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(users) > adminPageSize {
+		users = users[:adminPageSize]
+		page.NextURL = offsetPageURL(r, number+1)
+	}
+	if number > 1 {
+		page.PrevURL = offsetPageURL(r, number-1)
+	}
+	page.Users = users
+
+	page.FailedLogins, err = models.GetFailedLoginAttempts(adh.DB, adminFailedLogins)
+	if err != nil {
+		log.Printf("Failed to list failed login attempts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tmpl := template.Must(template.New("admin.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/admin.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing admin.html:", err)
+		return
+	}
+}
+
+// targetUser возвращает пользователя из адреса действия. Действия над собственным аккаунтом
+// запрещены, чтобы администратор не мог случайно потерять доступ к консоли.
+func (adh *AdminHandler) targetUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if adminID, _ := currentUserID(r); id == adminID {
+		adh.redirect(w, r, adminErrorKey, "You cannot do this to your own account")
+		return nil, false
+	}
+
+	user, err := models.GetUserByID(adh.DB, id)
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// redirect возвращает администратора в консоль с сообщением о результате действия.
+func (adh *AdminHandler) redirect(w http.ResponseWriter, r *http.Request, key, message string) {
+	if err := addFlash(w, r, key, message); err != nil {
+		log.Println("Can't save session:", err)
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// DisableUser отключает аккаунт: пользователь выходит на всех устройствах, а его токены перестают действовать.
+func (adh *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := adh.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := models.DisableUser(adh.DB, user.ID)
+	if errors.Is(err, models.ErrLastAdmin) {
+		adh.redirect(w, r, adminErrorKey, "Cannot change "+user.Email+": "+err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to disable user %d: %v", user.ID, err)
+		http.Error(w, "Failed to disable the account", http.StatusInternalServerError)
+		return
+	}
+
+	adminID, _ := currentUserID(r)
+	log.Printf("Administrator %d disabled user %d", adminID, user.ID)
+	audit(adh.DB, r, userEvent(models.AuditUserDisable, user.ID, user.Email))
+	adh.redirect(w, r, adminNoticeKey, "Disabled "+user.Email)
+}
+
+func (adh *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := adh.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := models.EnableUser(adh.DB, user.ID); err != nil {
+		log.Printf("Failed to enable user %d: %v", user.ID, err)
+		http.Error(w, "Failed to enable the account", http.StatusInternalServerError)
+		return
+	}
+
+	adminID, _ := currentUserID(r)
+	log.Printf("Administrator %d enabled user %d", adminID, user.ID)
+	audit(adh.DB, r, userEvent(models.AuditUserEnable, user.ID, user.Email))
+	adh.redirect(w, r, adminNoticeKey, "Enabled "+user.Email)
+}
+
+// ResetUserPassword стирает пароль пользователя, завершает его сессии, отзывает токены API
+// и отправляет ему ссылку для выбора нового пароля.
+func (adh *AdminHandler) ResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := adh.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := models.ForcePasswordReset(adh.DB, user.ID)
+	if errors.Is(err, models.ErrLastAdmin) {
+		adh.redirect(w, r, adminErrorKey, "Cannot change "+user.Email+": "+err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to reset password of user %d: %v", user.ID, err)
+		http.Error(w, "Failed to reset the password", http.StatusInternalServerError)
+		return
+	}
+
+	adminID, _ := currentUserID(r)
+	log.Printf("Administrator %d forced a password reset for user %d", adminID, user.ID)
+	audit(adh.DB, r, userEvent(models.AuditPasswordReset, user.ID, user.Email))
+	adh.sendForcedPasswordReset(user)
+	adh.redirect(w, r, adminNoticeKey, "Sent a password reset link to "+user.Email)
+}
+
+func (adh *AdminHandler) sendForcedPasswordReset(user *models.User) {
+	link, err := adh.Auth.passwordResetURL(user)
+	if err != nil {
+		log.Printf("Failed to create password reset for user %d: %v", user.ID, err)
+		return
+	}
+
+	adh.Auth.sendMail(user.ID, mail.Message{
+		To:      user.Email,
+		Subject: "Your Notes password has been reset",
+		Body: "An administrator has reset the password for your Notes account, " +
+			"signed it out on all devices and revoked its API tokens.\n\n" +
+			"To choose a new password, open this link within an hour:\n" +
+			link + "\n\n" +
+			"If the link has expired, request a new one on the sign-in page.\n",
+	})
+}
+
+// DeleteUser удаляет пользователя со всеми его заметками.
+func (adh *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := adh.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := models.DeleteUser(adh.DB, user.ID)
+	if errors.Is(err, models.ErrLastAdmin) {
+		adh.redirect(w, r, adminErrorKey, "Cannot change "+user.Email+": "+err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete user %d: %v", user.ID, err)
+		http.Error(w, "Failed to delete the account", http.StatusInternalServerError)
+		return
+	}
+
+	adminID, _ := currentUserID(r)
+	log.Printf("Administrator %d deleted user %d", adminID, user.ID)
+	audit(adh.DB, r, userEvent(models.AuditUserDelete, user.ID, user.Email))
+	adh.redirect(w, r, adminNoticeKey, "Deleted "+user.Email)
+}
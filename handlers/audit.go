@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+const (
+	auditPageSize   = 50
+	auditDateLayout = "2006-01-02"
+)
+
+var errInvalidAuditFilter = errors.New("invalid audit log filter")
+
+// recordAudit сохраняет событие журнала аудита. Ошибка записи только попадает в лог,
+// чтобы журнал не мешал самому действию.
+func recordAudit(db *sqlx.DB, event models.AuditEvent) {
+	if err := event.RecordAuditEvent(db); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Action, err)
+	}
+}
+
+// audit сохраняет событие с адресом клиента. Если исполнитель не указан, им считается вошедший пользователь.
+func audit(db *sqlx.DB, r *http.Request, event models.AuditEvent) {
+	if event.ActorID == nil && event.ActorEmail == "" {
+		if userID, ok := currentUserID(r); ok {
+			event.ActorID = &userID
+		}
+	}
+	event.IP = clientIP(r)
+	recordAudit(db, event)
+}
+
+// noteEvent описывает действие над заметкой noteID.
+func noteEvent(action string, noteID int, details string) models.AuditEvent {
+	return models.AuditEvent{Action: action, TargetType: models.AuditTargetNote, TargetID: &noteID, Details: details}
+}
+
+// userEvent описывает действие над аккаунтом userID.
+func userEvent(action string, userID int, details string) models.AuditEvent {
+	return models.AuditEvent{Action: action, TargetType: models.AuditTargetUser, TargetID: &userID, Details: details}
+}
+
+type auditPage struct {
+	Events []models.AuditEvent
+	// Admin — журнал всего приложения; иначе — события текущего пользователя.
+	Admin     bool
+	CurrentID int
+	Actions   []string
+	// Query — параметры фильтра для полей формы и ссылок на выгрузку.
+	Query      url.Values
+	ExportCSV  string
+	ExportJSON string
+	PrevURL    string
+	NextURL    string
+}
+
+// auditFilter читает фильтр журнала из строки запроса: действие, объект, исполнитель (только
+// в журнале администратора) и даты from/to включительно в формате ГГГГ-ММ-ДД.
+func auditFilter(r *http.Request, admin bool) (models.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+	}
+
+	if filter.Action != "" && !slices.Contains(models.AuditActions, filter.Action) {
+		return filter, errInvalidAuditFilter
+	}
+	switch filter.TargetType {
+	case "", models.AuditTargetNote, models.AuditTargetUser:
+	default:
+		return filter, errInvalidAuditFilter
+	}
+	if raw := query.Get("target_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			return filter, errInvalidAuditFilter
+		}
+		filter.TargetID = id
+	}
+	if admin {
+		filter.Actor = strings.TrimSpace(query.Get("actor"))
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.ParseInLocation(auditDateLayout, raw, time.Local)
+		if err != nil {
+			return filter, errInvalidAuditFilter
+		}
+		filter.From = from
+	}
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.ParseInLocation(auditDateLayout, raw, time.Local)
+		if err != nil {
+			return filter, errInvalidAuditFilter
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// offsetPageURL возвращает адрес текущего списка на странице page; первая страница — без параметра.
+func offsetPageURL(r *http.Request, page int) string {
+	query := r.URL.Query()
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	} else {
+		query.Del("page")
+	}
+
+	if len(query) == 0 {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// pageNumber читает номер страницы из параметра page; по умолчанию первая.
+func pageNumber(r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("page")
+	if raw == "" {
+		return 1, true
+	}
+	page, err := strconv.Atoi(raw)
+	return page, err == nil && page >= 1
+}
+
+// renderAudit показывает страницу журнала, отобранную filter; export — адрес выгрузки.
+func renderAudit(
+	w http.ResponseWriter, r *http.Request, db *sqlx.DB, filter models.AuditFilter, export string, page auditPage,
+) {
+	number, ok := pageNumber(r)
+	if !ok {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	// Запрашиваем на одно событие больше, чтобы узнать, есть ли следующая страница.
+	events, err := models.SearchAuditEvents(db, filter, auditPageSize+1, (number-1)*auditPageSize)
+	if err != nil {
+		log.Printf("Failed to search audit log: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(events) > auditPageSize {
+		events = events[:auditPageSize]
+		page.NextURL = offsetPageURL(r, number+1)
+	}
+	if number > 1 {
+		page.PrevURL = offsetPageURL(r, number-1)
+	}
+
+	query := r.URL.Query()
+	query.Del("page")
+	page.Events = events
+	page.Actions = models.AuditActions
+	page.Query = query
+	page.ExportCSV = exportURL(export, query, "csv")
+	page.ExportJSON = exportURL(export, query, "json")
+
+	tmpl := template.Must(template.New("audit.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/audit.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing audit.html:", err)
+		return
+	}
+}
+
+// exportURL возвращает адрес выгрузки журнала с тем же фильтром в формате format.
+func exportURL(path string, filter url.Values, format string) string {
+	query := url.Values{}
+	for key, values := range filter {
+		query[key] = values
+	}
+	query.Set("format", format)
+	return path + "?" + query.Encode()
+}
+
+// exportAudit выгружает до models.MaxAuditExport событий в формате CSV (по умолчанию) или JSON (format=json).
+func exportAudit(w http.ResponseWriter, r *http.Request, db *sqlx.DB, filter models.AuditFilter, name string) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "json" {
+		http.Error(w, "Format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	events, err := models.SearchAuditEvents(db, filter, models.MaxAuditExport, 0)
+	if err != nil {
+		log.Printf("Failed to export audit log: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	filename := name + "-" + time.Now().Format(auditDateLayout)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if format == "json" {
+		if events == nil {
+			events = []models.AuditEvent{}
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		writeJSON(w, http.StatusOK, events)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+	if err := writeAuditCSV(w, events); err != nil {
+		log.Printf("Failed to write audit log export: %v", err)
+	}
+}
+
+func writeAuditCSV(w io.Writer, events []models.AuditEvent) error {
+	out := csv.NewWriter(w)
+	header := []string{"time", "actor_id", "actor_email", "action", "target_type", "target_id", "details", "ip"}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		record := []string{
+			event.CreatedAt.UTC().Format(time.RFC3339),
+			optionalID(event.ActorID),
+			csvSafe(event.ActorEmail),
+			event.Action,
+			event.TargetType,
+			optionalID(event.TargetID),
+			csvSafe(event.Details),
+			event.IP,
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func optionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
+
+// csvSafe не даёт табличным редакторам выполнить значение как формулу: заголовок заметки
+// вида "=HYPERLINK(...)" выгружается как текст.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Activity показывает пользователю его действия и события, касающиеся его аккаунта.
+func (ach *AccountHandler) Activity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	filter, err := auditFilter(r, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.UserID = userID
+
+	renderAudit(w, r, ach.DB, filter, "/account/activity/export", auditPage{CurrentID: userID})
+}
+
+// ExportActivity выгружает события пользователя в CSV или JSON.
+func (ach *AccountHandler) ExportActivity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	filter, err := auditFilter(r, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.UserID = userID
+
+	exportAudit(w, r, ach.DB, filter, "activity")
+}
+
+// AuditLog показывает администратору журнал всего приложения с поиском.
+func (adh *AdminHandler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	renderAudit(w, r, adh.DB, filter, "/admin/audit/export", auditPage{Admin: true, CurrentID: userID})
+}
+
+// ExportAuditLog выгружает найденные события журнала в CSV или JSON.
+func (adh *AdminHandler) ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exportAudit(w, r, adh.DB, filter, "audit-log")
+}
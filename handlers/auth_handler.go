@@ -1,31 +1,78 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 
+	"NotesWebApp/mail"
 	"NotesWebApp/models"
+	"NotesWebApp/sso"
+	"NotesWebApp/validation"
 )
 
+const mailTimeout = 30 * time.Second
+
+const errAccountDisabled = "This account has been disabled, contact the administrator"
+
 type AuthHandler struct {
-	DB *sqlx.DB
+	DB     *sqlx.DB
+	Mailer mail.Sender
+	// BaseURL — внешний адрес приложения (APP_URL) для ссылок в письмах.
+	BaseURL            string
+	VerificationPolicy VerificationPolicy
+	// SSO — вход через OpenID Connect; nil, если провайдер не настроен.
+	SSO            *sso.Provider
+	PasswordPolicy validation.PasswordPolicy
+	// AdminEmail — адрес, владелец которого становится администратором при входе,
+	// пока в приложении нет ни одного администратора.
+	AdminEmail string
+}
+
+func NewAuthHandler(
+	db *sqlx.DB, mailer mail.Sender, baseURL string, policy VerificationPolicy, provider *sso.Provider,
+	passwords validation.PasswordPolicy, adminEmail string,
+) *AuthHandler {
+	return &AuthHandler{
+		DB:                 db,
+		Mailer:             mailer,
+		BaseURL:            baseURL,
+		VerificationPolicy: policy,
+		SSO:                provider,
+		PasswordPolicy:     passwords,
+		AdminEmail:         strings.TrimSpace(adminEmail),
+	}
 }
 
-func NewAuthHandler(db *sqlx.DB) *AuthHandler {
-	return &AuthHandler{DB: db}
+type loginPage struct {
+	PasswordReset bool
+	Registered    bool
+	Unverified    bool
+	Error         string
+	SSOName       string
+}
+
+// sendMail отправляет письмо пользователю в фоне, чтобы время ответа не зависело от почтового сервера.
+func (ah *AuthHandler) sendMail(userID int, msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := ah.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q to user %d: %v", msg.Subject, userID, err)
+		}
+	}()
 }
 
 func (ah *AuthHandler) Index(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionName)
-	if err != nil {
-		http.Error(w, "Failed to get session", http.StatusBadRequest)
-	}
-	_, ok := session.Values["userID"].(int)
-	if !ok {
+	if _, ok := currentUserID(r); !ok {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
@@ -33,30 +80,89 @@ func (ah *AuthHandler) Index(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/notes", http.StatusFound)
 }
 
-func (ah *AuthHandler) LoginForm(w http.ResponseWriter, _ *http.Request) {
-	tmpl := template.Must(template.ParseFiles("templates/login.html"))
-	err := tmpl.Execute(w, nil)
+func (ah *AuthHandler) renderLogin(w http.ResponseWriter, r *http.Request, status int, page loginPage) {
+	if ah.SSO != nil {
+		page.SSOName = ah.SSO.Name
+	}
+
+	tmpl := template.Must(template.New("login.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/login.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
 	if err != nil {
 		log.Println("Error while executing login.html template:", err)
 		return
 	}
 }
 
+func (ah *AuthHandler) LoginForm(w http.ResponseWriter, r *http.Request) {
+	ah.renderLogin(w, r, http.StatusOK, loginPage{
+		PasswordReset: r.URL.Query().Get("reset") != "",
+		Registered:    r.URL.Query().Get("registered") != "",
+	})
+}
+
+// Login проверяет пароль. Ответ на неверный адрес и неверный пароль одинаков, а после повторных
+// неудач попытки для аккаунта и для адреса клиента замедляются и временно блокируются.
 func (ah *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	email := r.FormValue("email")
+	email := strings.TrimSpace(r.FormValue("email"))
 	password := r.FormValue("password")
+	key := throttleKey(email)
+	ip := clientIP(r)
 
-	user, err := models.GetUserByEmail(ah.DB, email)
+	wait, err := ah.loginRetryAfter(key, ip)
 	if err != nil {
-		http.Error(w, "User not found: invalid credentials", http.StatusUnauthorized)
+		log.Printf("Failed to check login attempts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		ah.renderLoginThrottled(w, r, wait)
+		return
+	}
+
+	user, err := models.GetUserByEmail(ah.DB, email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		user = nil
+	case err != nil:
+		log.Printf("Failed to look up user: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		http.Error(w, "Wrong password", http.StatusUnauthorized)
+	if !checkPassword(user, password) {
+		var userID *int
+		if user != nil {
+			userID = &user.ID
+		}
+		ah.recordLoginAttempt(key, userID, ip, false)
+		ah.renderLogin(w, r, http.StatusUnauthorized, loginPage{Error: errInvalidCredentials})
 		return
 	}
 
+	// Об отключении сообщаем только после проверки пароля, чтобы не раскрывать состояние чужих аккаунтов.
+	if user.IsDisabled() {
+		ah.renderLogin(w, r, http.StatusForbidden, loginPage{Error: errAccountDisabled})
+		return
+	}
+
+	if ah.VerificationPolicy == VerificationForLogin && !user.IsVerified() {
+		ah.renderLogin(w, r, http.StatusForbidden, loginPage{Unverified: true})
+		return
+	}
+
+	// С включённой двухфакторной аутентификацией попытка считается успешной только после ввода кода.
+	if user.TwoFactorEnabled() {
+		ah.beginTwoFactor(w, r, user.ID)
+		return
+	}
+
+	ah.recordLoginAttempt(key, &user.ID, ip, true)
+	ah.signIn(w, r, user)
+}
+
+// signIn начинает сессию пользователя с новым токеном, чтобы токен, известный до входа, стал бесполезен.
+func (ah *AuthHandler) signIn(w http.ResponseWriter, r *http.Request, user *models.User) {
 	session, err := store.Get(r, sessionName)
 	if err != nil {
 		log.Printf("Failed to get session: %v", err)
@@ -64,6 +170,14 @@ func (ah *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to get session", http.StatusBadRequest)
 		return
 	}
+	if err := store.Renew(session); err != nil {
+		log.Printf("Failed to renew session: %v", err)
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	delete(session.Values, csrfSessionKey) // после входа форма получит новый токен
+	clearPendingLogin(session)
 	session.Values["userID"] = user.ID
 	err = session.Save(r, w)
 	if err != nil {
@@ -71,22 +185,84 @@ func (ah *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Println("User logged in successfully:", user.ID)
+	audit(ah.DB, r, models.AuditEvent{ActorID: &user.ID, Action: models.AuditLogin})
+
+	if err := models.UpdateLastLogin(ah.DB, user.ID); err != nil {
+		log.Printf("Failed to update last login of user %d: %v", user.ID, err)
+	}
+	ah.bootstrapAdmin(user)
+
 	http.Redirect(w, r, "/notes", http.StatusFound)
 }
 
-func (ah *AuthHandler) RegisterForm(w http.ResponseWriter, _ *http.Request) {
-	tmpl := template.Must(template.ParseFiles("templates/register.html"))
-	err := tmpl.Execute(w, nil)
+// bootstrapAdmin назначает первого администратора: им становится владелец адреса AdminEmail,
+// подтвердивший его, если других администраторов ещё нет.
+func (ah *AuthHandler) bootstrapAdmin(user *models.User) {
+	if ah.AdminEmail == "" || user.IsAdmin() || !strings.EqualFold(user.Email, ah.AdminEmail) {
+		return
+	}
+
+	promoted, err := models.PromoteFirstAdmin(ah.DB, user.ID, ah.AdminEmail)
+	if err != nil {
+		log.Printf("Failed to promote user %d to administrator: %v", user.ID, err)
+		return
+	}
+	if promoted {
+		log.Printf("User %d is now the administrator", user.ID)
+	}
+}
+
+type registerPage struct {
+	Email  string
+	Errors validation.Errors
+}
+
+func (ah *AuthHandler) renderRegister(w http.ResponseWriter, r *http.Request, status int, page registerPage) {
+	tmpl := template.Must(template.New("register.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/register.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
 	if err != nil {
 		log.Println("Error while executing register.html template:", err)
 		return
 	}
 }
 
+func (ah *AuthHandler) RegisterForm(w http.ResponseWriter, r *http.Request) {
+	ah.renderRegister(w, r, http.StatusOK, registerPage{})
+}
+
 func (ah *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	email := r.FormValue("email")
+	page := registerPage{Email: strings.TrimSpace(r.FormValue("email")), Errors: validation.Errors{}}
 	password := r.FormValue("password")
 
+	email, err := validation.Email(page.Email)
+	if err != nil {
+		page.Errors.Add("email", err)
+	}
+	if err := ah.PasswordPolicy.Check(password, email); err != nil {
+		page.Errors.Add("password", err)
+	}
+	if password != r.FormValue("confirm_password") {
+		page.Errors.Add("confirm_password", errors.New("passwords do not match"))
+	}
+
+	if email != "" {
+		exists, err := models.EmailExists(ah.DB, email)
+		if err != nil {
+			log.Printf("Failed to check email: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			page.Errors.Add("email", models.ErrDuplicateEmail)
+		}
+	}
+
+	if len(page.Errors) > 0 {
+		ah.renderRegister(w, r, http.StatusBadRequest, page)
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Failed to hash password for user %s: %v", email, err)
@@ -100,16 +276,31 @@ func (ah *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Password: string(hashedPassword),
 	}
 
-	if err := user.CreateUser(ah.DB); err != nil {
+	err = user.CreateUser(ah.DB)
+	if errors.Is(err, models.ErrDuplicateEmail) {
+		page.Errors.Add("email", err)
+		ah.renderRegister(w, r, http.StatusBadRequest, page)
+		return
+	}
+	if err != nil {
 		log.Printf("Failed to create user %s: %v", email, err)
 
 		http.Error(w, "Internal server error: failed to create user", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("user created %+v", user)
+	log.Printf("User %d registered", user.ID)
+	ah.auditRegistration(r, &user, "")
+	ah.sendEmailVerification(&user)
+
+	http.Redirect(w, r, "/login?registered=1", http.StatusSeeOther)
+}
 
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+// auditRegistration записывает регистрацию от имени нового пользователя: до входа он ещё не в сессии.
+func (ah *AuthHandler) auditRegistration(r *http.Request, user *models.User, details string) {
+	event := userEvent(models.AuditRegister, user.ID, details)
+	event.ActorID = &user.ID
+	audit(ah.DB, r, event)
 }
 
 func (ah *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
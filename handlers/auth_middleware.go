@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+type contextKey int
+
+const (
+	userIDContextKey contextKey = iota
+	authMethodContextKey
+)
+
+const (
+	authMethodSession = "session"
+	authMethodToken   = "token"
+)
+
+type AuthMiddleware struct {
+	DB *sqlx.DB
+}
+
+func NewAuthMiddleware(db *sqlx.DB) *AuthMiddleware {
+	return &AuthMiddleware{DB: db}
+}
+
+// Authenticate определяет пользователя по заголовку Authorization: Bearer <token>
+// или по сессионной cookie и кладёт его идентификатор в контекст запроса.
+func (am *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("Authorization"); header != "" {
+			userID, ok := am.tokenUserID(header)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="notes", error="invalid_token"`)
+				writeAPIError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired access token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withUser(r.Context(), userID, authMethodToken)))
+			return
+		}
+
+		session, err := store.Get(r, sessionName)
+		if err != nil {
+			log.Printf("Failed to get session: %v", err)
+		}
+		if userID, ok := session.Values["userID"].(int); ok {
+			r = r.WithContext(withUser(r.Context(), userID, authMethodSession))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireSession пропускает к next только пользователей, вошедших через браузер. Bearer-токены
+// выдаются для работы с заметками, поэтому ими нельзя выпускать новые токены, завершать сессии
+// и менять настройки второго фактора: иначе утёкший токен позволил бы сохранить доступ навсегда.
+func RequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := currentUserID(r); !ok {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if authMethod(r) != authMethodSession {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (am *AuthMiddleware) tokenUserID(header string) (int, bool) {
+	scheme, plain, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || plain == "" {
+		return 0, false
+	}
+
+	token, err := models.GetAPITokenByHash(am.DB, models.HashToken(strings.TrimSpace(plain)))
+	if err != nil {
+		log.Printf("Failed to look up access token: %v", err)
+		return 0, false
+	}
+	if token == nil || token.IsExpired(time.Now()) {
+		return 0, false
+	}
+
+	if err := token.TouchAPIToken(am.DB); err != nil {
+		log.Printf("Failed to update last use of token %d: %v", token.ID, err)
+	}
+
+	return token.UserID, true
+}
+
+func withUser(ctx context.Context, userID int, method string) context.Context {
+	ctx = context.WithValue(ctx, userIDContextKey, userID)
+	return context.WithValue(ctx, authMethodContextKey, method)
+}
+
+// currentUserID возвращает идентификатор пользователя, установленный Authenticate.
+func currentUserID(r *http.Request) (int, bool) {
+	userID, ok := r.Context().Value(userIDContextKey).(int)
+	return userID, ok
+}
+
+// authMethod возвращает способ, которым Authenticate определил пользователя: сессия или Bearer-токен.
+func authMethod(r *http.Request) string {
+	method, _ := r.Context().Value(authMethodContextKey).(string)
+	return method
+}
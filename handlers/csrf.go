@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+)
+
+const (
+	csrfSessionKey = "csrfToken"
+	csrfFormField  = "csrf_token"
+	csrfHeader     = "X-CSRF-Token"
+	csrfCookieName = "csrf"
+	csrfTokenBytes = 32
+	// csrfCookieMaxAge — время жизни токена анонимного посетителя, например открытой формы входа.
+	csrfCookieMaxAge = 12 * 60 * 60
+)
+
+// csrfToken возвращает токен CSRF текущего посетителя. Вошедший пользователь получает токен,
+// сохранённый в его сессии. Анонимному посетителю токен выдаётся в подписанной cookie
+// (double-submit), чтобы каждый GET страницы входа или регистрации не создавал запись сессии в базе.
+func csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+	}
+	if token, ok := session.Values[csrfSessionKey].(string); ok && token != "" {
+		return token, nil
+	}
+	anonymous := sessionUserID(session) == nil
+	if anonymous {
+		if token := cookieCSRFToken(r); token != "" {
+			return token, nil
+		}
+	}
+
+	buf := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	// Значение в сессии нужно и анонимному посетителю: повторный вызов в том же запросе вернёт тот же токен.
+	session.Values[csrfSessionKey] = token
+	if anonymous {
+		return token, setCSRFCookie(w, token)
+	}
+	return token, session.Save(r, w)
+}
+
+// cookieCSRFToken возвращает токен из подписанной cookie или пустую строку, если её нет или подпись неверна.
+func cookieCSRFToken(r *http.Request) string {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil {
+		return ""
+	}
+	var token string
+	if err := securecookie.DecodeMulti(csrfCookieName, cookie.Value, &token, store.Codecs...); err != nil {
+		return ""
+	}
+	return token
+}
+
+func setCSRFCookie(w http.ResponseWriter, token string) error {
+	encoded, err := securecookie.EncodeMulti(csrfCookieName, token, store.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    encoded,
+		Path:     "/",
+		MaxAge:   csrfCookieMaxAge,
+		Secure:   store.Options.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// csrfFuncs возвращает функцию шаблона csrfField, которая выводит скрытое поле с токеном для форм.
+// Токен выдаётся до вывода шаблона, пока ещё можно выставить cookie.
+func csrfFuncs(w http.ResponseWriter, r *http.Request) template.FuncMap {
+	token, err := csrfToken(w, r)
+	if err != nil {
+		log.Println("Failed to issue CSRF token:", err)
+	}
+	return template.FuncMap{
+		"csrfField": func() template.HTML {
+			field := `<input type="hidden" name="` + csrfFormField + `" value="` + template.HTMLEscapeString(token) + `">`
+			return template.HTML(field) //nolint:gosec // значение экранировано
+		},
+	}
+}
+
+// validCSRFToken сверяет токен из формы или заголовка X-CSRF-Token с токеном сессии, а у анонимного
+// посетителя — также с токеном из cookie. Токен из cookie после входа не принимается.
+func validCSRFToken(r *http.Request) bool {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+	}
+
+	got := r.Header.Get(csrfHeader)
+	if got == "" {
+		got = r.PostFormValue(csrfFormField)
+	}
+	if got == "" {
+		return false
+	}
+
+	var expected []string
+	if token, ok := session.Values[csrfSessionKey].(string); ok {
+		expected = append(expected, token)
+	}
+	if sessionUserID(session) == nil {
+		expected = append(expected, cookieCSRFToken(r))
+	}
+	for _, token := range expected {
+		if token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// CSRFProtect отклоняет изменяющие запросы без действительного токена CSRF.
+// Запросы с Bearer-токеном не используют cookie и поэтому не проверяются.
+func CSRFProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authMethod(r) == authMethodToken {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		isAPI := strings.HasPrefix(r.URL.Path, "/api/")
+		if isSafeMethod(r.Method) {
+			// Клиенты API, вошедшие по сессии, получают токен в заголовке ответа.
+			if isAPI && authMethod(r) == authMethodSession {
+				if token, err := csrfToken(w, r); err == nil {
+					w.Header().Set(csrfHeader, token)
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Форму разбираем с ограничением размера: иначе PostFormValue прочитал бы её целиком без лимита.
+		if !isAPI && !parseForm(w, r) {
+			return
+		}
+
+		if validCSRFToken(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Printf("Rejected %s %s: missing or invalid CSRF token", r.Method, r.URL.Path)
+		if isAPI {
+			writeAPIError(w, http.StatusForbidden, "csrf_failed",
+				"Missing or invalid CSRF token; send it in the "+csrfHeader+" header or use a bearer token")
+			return
+		}
+		tmpl := template.Must(template.ParseFiles("templates/csrf.html"))
+		w.WriteHeader(http.StatusForbidden)
+		err := tmpl.Execute(w, nil)
+		if err != nil {
+			log.Println("Error while executing csrf.html:", err)
+			return
+		}
+	})
+}
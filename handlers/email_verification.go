@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"NotesWebApp/mail"
+	"NotesWebApp/models"
+)
+
+// VerificationPolicy определяет, что запрещено пользователю до подтверждения адреса электронной почты.
+type VerificationPolicy string
+
+const (
+	// VerificationOptional — подтверждение адреса ничего не ограничивает.
+	VerificationOptional VerificationPolicy = "optional"
+	// VerificationForNotes — без подтверждения нельзя создавать заметки.
+	VerificationForNotes VerificationPolicy = "notes"
+	// VerificationForLogin — без подтверждения нельзя войти.
+	VerificationForLogin VerificationPolicy = "login"
+)
+
+var (
+	// emailVerificationThrottle ограничивает повторные письма подтверждения на один адрес: после каждого
+	// запроса следующий принимается не раньше чем через удваивающуюся паузу.
+	emailVerificationThrottle = models.LoginThrottle{
+		Window:          24 * time.Hour,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAfter:    10,
+		LockoutDuration: 24 * time.Hour,
+	}
+	// ipEmailVerificationThrottle ограничивает повторные письма подтверждения на разные адреса с одного IP.
+	ipEmailVerificationThrottle = models.LoginThrottle{
+		Window:          time.Hour,
+		FreeAttempts:    10,
+		BaseDelay:       time.Minute,
+		MaxDelay:        15 * time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: time.Hour,
+	}
+)
+
+type verifyEmailPage struct {
+	User     *models.User
+	Verified bool
+	Sent     bool
+	Error    string
+}
+
+func (ah *AuthHandler) renderVerifyEmail(w http.ResponseWriter, r *http.Request, status int, page verifyEmailPage) {
+	tmpl := template.Must(template.New("verify_email.html").Funcs(csrfFuncs(w, r)).
+		ParseFiles("templates/verify_email.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing verify_email.html:", err)
+		return
+	}
+}
+
+// sendEmailVerification создаёт ссылку подтверждения адреса и отправляет её пользователю.
+func (ah *AuthHandler) sendEmailVerification(user *models.User) {
+	plain, err := models.GenerateEmailVerificationToken()
+	if err != nil {
+		log.Printf("Failed to generate email verification token: %v", err)
+		return
+	}
+
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(models.EmailVerificationLifetime),
+	}
+	if err := verification.CreateEmailVerification(ah.DB, plain); err != nil {
+		log.Printf("Failed to create email verification for user %d: %v", user.ID, err)
+		return
+	}
+
+	ah.sendMail(user.ID, mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address for Notes",
+		Body: "Thanks for signing up for Notes.\n\n" +
+			"To confirm your email address, open this link within 48 hours:\n" +
+			ah.BaseURL + "/verify-email/" + plain + "\n\n" +
+			"If you did not create an account, ignore this email.\n",
+	})
+}
+
+// VerifyEmailForm показывает состояние подтверждения и форму повторной отправки письма.
+func (ah *AuthHandler) VerifyEmailForm(w http.ResponseWriter, r *http.Request) {
+	page := verifyEmailPage{}
+	if userID, ok := currentUserID(r); ok {
+		user, err := models.GetUserByID(ah.DB, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.User = user
+	}
+
+	ah.renderVerifyEmail(w, r, http.StatusOK, page)
+}
+
+// ResendVerification отправляет новую ссылку подтверждения. Вошедшему пользователю — на его адрес,
+// остальным — на адрес из формы. Ответ не зависит от того, зарегистрирован ли адрес.
+// Повторные письма на один адрес и с одного IP ограничиваются.
+func (ah *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var (
+		user  *models.User
+		page  verifyEmailPage
+		email string
+		err   error
+	)
+	if userID, ok := currentUserID(r); ok {
+		user, err = models.GetUserByID(ah.DB, userID)
+		if user != nil {
+			page.User, email = user, user.Email
+		}
+	} else {
+		email = strings.TrimSpace(r.FormValue("email"))
+		user, err = models.GetUserByEmail(ah.DB, email)
+		if errors.Is(err, sql.ErrNoRows) {
+			user, err = nil, nil
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to look up user for email verification: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	checks := []throttleCheck{
+		{emailVerificationThrottle, models.ThrottleEmailVerification, throttleKey(email)},
+		{ipEmailVerificationThrottle, models.ThrottleEmailVerificationIP, clientIP(r)},
+	}
+	wait, err := throttleWait(ah.DB, checks...)
+	if err != nil {
+		log.Printf("Failed to check email verification requests: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		page.Error = "Too many requests for a new link. Try again in " + retryAfter(w, wait)
+		ah.renderVerifyEmail(w, r, http.StatusTooManyRequests, page)
+		return
+	}
+	recordThrottle(ah.DB, checks...)
+
+	if user != nil && !user.IsVerified() {
+		ah.sendEmailVerification(user)
+	}
+
+	ah.renderVerifyEmail(w, r, http.StatusOK, verifyEmailPage{Sent: true})
+}
+
+// VerifyEmail подтверждает адрес по ссылке из письма.
+func (ah *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	// Токен находится в URL, поэтому не передаём его дальше через Referer.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	userID, err := models.VerifyEmail(ah.DB, mux.Vars(r)["token"])
+	if errors.Is(err, models.ErrEmailVerificationInvalid) {
+		ah.renderVerifyEmail(w, r, http.StatusGone, verifyEmailPage{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to verify email: %v", err)
+		http.Error(w, "Internal server error: failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d verified their email address", userID)
+	ah.renderVerifyEmail(w, r, http.StatusOK, verifyEmailPage{Verified: true})
+}
+
+// RequireVerifiedEmail не пропускает к next пользователей с неподтверждённым адресом,
+// если политика EMAIL_VERIFICATION этого требует. Запросы без пользователя передаются next как есть.
+func (ah *AuthHandler) RequireVerifiedEmail(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUserID(r)
+		if ah.VerificationPolicy == VerificationOptional || !ok {
+			next(w, r)
+			return
+		}
+
+		user, err := models.GetUserByID(ah.DB, userID)
+		if err != nil {
+			log.Printf("Failed to get user %d: %v", userID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if user != nil && !user.IsVerified() {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeAPIError(w, http.StatusForbidden, "email_not_verified", "Confirm your email address first")
+				return
+			}
+			http.Redirect(w, r, "/verify-email", http.StatusSeeOther)
+			return
+		}
+
+		next(w, r)
+	}
+}
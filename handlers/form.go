@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"NotesWebApp/models"
+)
+
+const (
+	// maxFormSize ограничивает весь запрос формы; самая большая из них — форма заметки с вложениями.
+	maxFormSize = 4 * models.MaxAttachmentSize
+	// maxFormMemory — часть формы, которая держится в памяти; остальное пишется во временные файлы.
+	maxFormMemory = 8 << 20
+)
+
+// parseForm разбирает обычную или multipart-форму с ограничением размера. При ошибке ответ уже отправлен.
+func parseForm(w http.ResponseWriter, r *http.Request) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+
+	err := r.ParseMultipartForm(maxFormMemory)
+	if err == nil || errors.Is(err, http.ErrNotMultipart) {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "Upload is too large", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	http.Error(w, "Invalid form data", http.StatusBadRequest)
+	return false
+}
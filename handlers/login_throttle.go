@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"NotesWebApp/models"
+)
+
+const errInvalidCredentials = "Invalid email or password"
+
+var (
+	// accountLoginThrottle ограничивает подбор пароля к одному аккаунту с любых адресов.
+	accountLoginThrottle = models.LoginThrottle{
+		Window:          24 * time.Hour,
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+	}
+	// ipLoginThrottle ограничивает перебор разных аккаунтов с одного адреса.
+	ipLoginThrottle = models.LoginThrottle{
+		Window:          time.Hour,
+		FreeAttempts:    20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    100,
+		LockoutDuration: time.Hour,
+	}
+)
+
+// dummyPasswordHash сравнивается с паролем, когда пользователя нет, чтобы время ответа
+// не выдавало, зарегистрирован ли адрес.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// checkPassword проверяет пароль пользователя за одинаковое время, даже если user равен nil
+// или у пользователя нет пароля (он входит только через SSO).
+func checkPassword(user *models.User, password string) bool {
+	hash := dummyPasswordHash()
+	if user != nil && user.Password != "" {
+		hash = []byte(user.Password)
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	return err == nil && user != nil && user.Password != ""
+}
+
+// throttleKey приводит адрес к виду, в котором по нему считаются попытки входа.
+func throttleKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginRetryAfter возвращает, сколько ещё нужно подождать до следующей попытки входа в аккаунт email
+// с адреса ip, или 0, если попытку можно принять.
+func (ah *AuthHandler) loginRetryAfter(email, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	account, err := models.CountAccountFailures(ah.DB, email, now.Add(-accountLoginThrottle.Window))
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := models.CountIPFailures(ah.DB, ip, now.Add(-ipLoginThrottle.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	retryAt := accountLoginThrottle.RetryAt(account)
+	if at := ipLoginThrottle.RetryAt(byIP); at.After(retryAt) {
+		retryAt = at
+	}
+	return max(retryAt.Sub(now), 0), nil
+}
+
+// recordLoginAttempt сохраняет попытку входа; ошибка только пишется в лог, чтобы не мешать входу.
+func (ah *AuthHandler) recordLoginAttempt(email string, userID *int, ip string, succeeded bool) {
+	attempt := &models.LoginAttempt{Email: email, UserID: userID, IP: ip, Succeeded: succeeded}
+	if err := attempt.RecordLoginAttempt(ah.DB); err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+	if succeeded {
+		return
+	}
+
+	// Исполнитель неудачной попытки неизвестен, поэтому в журнал записывается только введённый адрес.
+	event := models.AuditEvent{ActorEmail: email, Action: models.AuditLoginFailed, IP: ip}
+	if userID != nil {
+		log.Printf("Failed login attempt for user %d from %s", *userID, ip)
+		event.TargetType, event.TargetID = models.AuditTargetUser, userID
+	}
+	recordAudit(ah.DB, event)
+}
+
+// renderLoginThrottled отвечает на попытку входа, пришедшую раньше, чем истекла пауза.
+func (ah *AuthHandler) renderLoginThrottled(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	ah.renderLogin(w, r, http.StatusTooManyRequests, loginPage{
+		Error: "Too many failed sign-in attempts. Try again in " + retryAfter(w, wait),
+	})
+}
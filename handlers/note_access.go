@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+type noteAccessError struct {
+	status  int
+	code    string
+	message string
+}
+
+// loadNote загружает заметку из URL и проверяет, что у пользователя есть доступ не ниже required.
+func loadNote(db *sqlx.DB, r *http.Request, userID int, required models.Access) (
+	*models.Note, models.Access, *noteAccessError,
+) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, models.AccessNone, &noteAccessError{http.StatusBadRequest, "invalid_id", "Invalid note ID"}
+	}
+
+	note, err := models.GetNoteByID(db, id)
+	if err != nil {
+		log.Printf("Failed to get note %d: %v", id, err)
+		return nil, models.AccessNone, &noteAccessError{http.StatusInternalServerError, "internal_error",
+			"Failed to load note"}
+	}
+
+	if note == nil {
+		return nil, models.AccessNone, &noteAccessError{http.StatusNotFound, "not_found", "Note not found"}
+	}
+
+	access, err := models.NoteAccess(db, note, userID)
+	if err != nil {
+		log.Printf("Failed to check access of user %d to note %d: %v", userID, note.ID, err)
+		return nil, models.AccessNone, &noteAccessError{http.StatusInternalServerError, "internal_error",
+			"Failed to load note"}
+	}
+
+	if access < required {
+		log.Printf("User %d tried to access note %d belonging to user %d", userID, note.ID, note.UserID)
+		return nil, access, &noteAccessError{http.StatusForbidden, "forbidden", forbiddenMessage(required)}
+	}
+
+	return note, access, nil
+}
+
+func forbiddenMessage(required models.Access) string {
+	switch required {
+	case models.AccessOwner:
+		return "Only the owner of this note can do this"
+	case models.AccessEdit:
+		return "You do not have permission to edit this note"
+	case models.AccessNone, models.AccessView:
+	}
+	return "You do not have permission to access this note"
+}
+
+// noteWithAccess — вариант loadNote для HTML-страниц.
+func (nh *NoteHandler) noteWithAccess(w http.ResponseWriter, r *http.Request, userID int, required models.Access) (
+	*models.Note, models.Access, bool,
+) {
+	note, access, accessErr := loadNote(nh.DB, r, userID, required)
+	if accessErr != nil {
+		http.Error(w, accessErr.message, accessErr.status)
+		return nil, access, false
+	}
+	return note, access, true
+}
+
+// noteWithAccess — вариант loadNote для JSON API.
+func (nah *NoteAPIHandler) noteWithAccess(w http.ResponseWriter, r *http.Request, userID int, required models.Access) (
+	*models.Note, bool,
+) {
+	note, _, accessErr := loadNote(nah.DB, r, userID, required)
+	if accessErr != nil {
+		writeAPIError(w, accessErr.status, accessErr.code, accessErr.message)
+		return nil, false
+	}
+	return note, true
+}
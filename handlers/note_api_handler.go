@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+type NoteAPIHandler struct {
+	DB *sqlx.DB
+}
+
+func NewNoteAPIHandler(db *sqlx.DB) *NoteAPIHandler {
+	return &NoteAPIHandler{DB: db}
+}
+
+type noteRequest struct {
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Tags       []string `json:"tags"`
+	NotebookID *int     `json:"notebookId"`
+	Pinned     *bool    `json:"pinned"`
+	Archived   *bool    `json:"archived"`
+}
+
+type notesResponse struct {
+	Notes      []models.Note `json:"notes"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+type searchResult struct {
+	models.SearchResult
+	TitleHighlight string `json:"titleHighlight"`
+	Snippet        string `json:"snippet"`
+}
+
+type searchResponse struct {
+	Query   string         `json:"query"`
+	Results []searchResult `json:"results"`
+}
+
+// userID возвращает идентификатор текущего пользователя или отвечает 401.
+func (nah *NoteAPIHandler) userID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return 0, false
+	}
+	return userID, true
+}
+
+func writeNoteValidationError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, models.ErrEmptyTitle) || errors.Is(err, models.ErrTitleTooLong) ||
+		errors.Is(err, models.ErrEmptyContent) || errors.Is(err, models.ErrTagTooLong) ||
+		errors.Is(err, models.ErrTooManyTags) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", err.Error())
+		return true
+	}
+	return false
+}
+
+func (nah *NoteAPIHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := nah.userID(w, r)
+	if !ok {
+		return
+	}
+
+	note := models.Note{}
+	filter := models.NoteFilter{Tag: strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))}
+
+	if raw := r.URL.Query().Get("archived"); raw != "" {
+		archived, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_archived", "Parameter archived must be true or false")
+			return
+		}
+		filter.Archived = archived
+	}
+
+	if raw := r.URL.Query().Get("notebook"); raw != "" {
+		notebookID, ok := nah.notebookID(w, userID, raw)
+		if !ok {
+			return
+		}
+		filter.NotebookID = notebookID
+	}
+
+	pageReq, err := pageRequest(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_page", err.Error())
+		return
+	}
+
+	result, err := note.GetNotesByUser(nah.DB, userID, filter, pageReq)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		writeAPIError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is invalid or belongs to another sort order")
+		return
+	}
+	if err == nil {
+		err = models.LoadTagsForNotes(nah.DB, result.Notes)
+	}
+	if err != nil {
+		log.Printf("Failed to list notes for user %d: %v", userID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to list notes")
+		return
+	}
+
+	resp := notesResponse{Notes: result.Notes, NextCursor: result.NextCursor}
+	if resp.Notes == nil {
+		resp.Notes = []models.Note{}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// notebookID проверяет, что блокнот существует и принадлежит пользователю.
+func (nah *NoteAPIHandler) notebookID(w http.ResponseWriter, userID int, raw string) (int, bool) {
+	notebookID, err := ownedNotebookID(nah.DB, userID, raw)
+	if errors.Is(err, errInvalidNotebook) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_notebook", "Notebook not found")
+		return 0, false
+	}
+	if err != nil {
+		log.Printf("Failed to load notebook %q for user %d: %v", raw, userID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to load notebook")
+		return 0, false
+	}
+	return notebookID, true
+}
+
+// noteETag строит ETag заметки по счётчику версий.
+func noteETag(note *models.Note) string {
+	return `"` + strconv.Itoa(note.Version) + `"`
+}
+
+// checkIfMatch отвечает 412, если заголовок If-Match не совпадает с текущей версией заметки.
+// Запросы без If-Match не проверяются.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, note *models.Note) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+
+	etag := noteETag(note)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	w.Header().Set("ETag", etag)
+	writeAPIError(w, http.StatusPreconditionFailed, "precondition_failed", "Note was modified, fetch it again")
+	return false
+}
+
+// writeVersionConflict сообщает, что заметку изменили между чтением и записью.
+func writeVersionConflict(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") != "" {
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition_failed", "Note was modified, fetch it again")
+		return
+	}
+	writeAPIError(w, http.StatusConflict, "version_conflict", "Note was modified concurrently, fetch it again")
+}
+
+func (nah *NoteAPIHandler) SearchNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := nah.userID(w, r)
+	if !ok {
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing_query", "Query parameter q is required")
+		return
+	}
+
+	results, err := models.SearchNotes(nah.DB, userID, query)
+	if err != nil {
+		log.Printf("Failed to search notes for user %d: %v", userID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to search notes")
+		return
+	}
+
+	resp := searchResponse{Query: query, Results: make([]searchResult, 0, len(results))}
+	for i := range results {
+		resp.Results = append(resp.Results, searchResult{
+			SearchResult:   results[i],
+			TitleHighlight: string(results[i].HighlightedTitle()),
+			Snippet:        string(results[i].HighlightedSnippet()),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (nah *NoteAPIHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := nah.userID(w, r)
+	if !ok {
+		return
+	}
+
+	note, ok := nah.noteWithAccess(w, r, userID, models.AccessView)
+	if !ok {
+		return
+	}
+
+	if err := note.LoadTags(nah.DB); err != nil {
+		log.Printf("Failed to load tags of note %d: %v", note.ID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to load note")
+		return
+	}
+
+	w.Header().Set("ETag", noteETag(note))
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (nah *NoteAPIHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := nah.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req noteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	note := &models.Note{
+		Title:   req.Title,
+		Content: req.Content,
+		UserID:  userID,
+		Tags:    models.NormalizeTags(req.Tags),
+	}
+
+	if req.NotebookID != nil {
+		if note.NotebookID, ok = nah.notebookID(w, userID, strconv.Itoa(*req.NotebookID)); !ok {
+			return
+		}
+	}
+	if req.Pinned != nil {
+		note.Pinned = *req.Pinned
+	}
+	if req.Archived != nil {
+		note.Archived = *req.Archived
+	}
+
+	if err := note.Validate(); writeNoteValidationError(w, err) {
+		return
+	}
+	if err := models.ValidateTags(note.Tags); writeNoteValidationError(w, err) {
+		return
+	}
+
+	if err := note.CreateNote(nah.DB); err != nil {
+		log.Printf("Failed to create note for user %d: %v", userID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to create note")
+		return
+	}
+	audit(nah.DB, r, noteEvent(models.AuditNoteCreate, note.ID, note.Title))
+
+	w.Header().Set("Location", "/api/v1/notes/"+strconv.Itoa(note.ID))
+	w.Header().Set("ETag", noteETag(note))
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (nah *NoteAPIHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := nah.userID(w, r)
+	if !ok {
+		return
+	}
+
+	note, ok := nah.noteWithAccess(w, r, userID, models.AccessEdit)
+	if !ok {
+		return
+	}
+
+	if !checkIfMatch(w, r, note) {
+		return
+	}
+
+	var req noteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	note.Title = req.Title
+	note.Content = req.Content
+
+	if err := note.Validate(); writeNoteValidationError(w, err) {
+		return
+	}
+
+	// Теги заменяются, только если переданы в запросе.
+	var tags []string
+	if req.Tags != nil {
+		tags = models.NormalizeTags(req.Tags)
+		if err := models.ValidateTags(tags); writeNoteValidationError(w, err) {
+			return
+		}
+	}
+
+	// Блокнот, закрепление и архив меняет только владелец.
+	if (req.NotebookID != nil || req.Pinned != nil || req.Archived != nil) && note.UserID != userID {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "Only the owner can move, pin or archive a note")
+		return
+	}
+
+	notebookID := 0
+	if req.NotebookID != nil {
+		if notebookID, ok = nah.notebookID(w, userID, strconv.Itoa(*req.NotebookID)); !ok {
+			return
+		}
+	}
+
+	if notebookID != 0 {
+		note.NotebookID = notebookID
+	}
+	if req.Pinned != nil {
+		note.Pinned = *req.Pinned
+	}
+	if req.Archived != nil {
+		note.Archived = *req.Archived
+	}
+
+	// Текст, теги, блокнот и флаги сохраняются одной транзакцией: запрос применяется целиком или никак.
+	if err := note.UpdateNote(nah.DB, userID, tags); err != nil {
+		if errors.Is(err, models.ErrVersionConflict) {
+			writeVersionConflict(w, r)
+			return
+		}
+		log.Printf("Failed to update note %d: %v", note.ID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to update note")
+		return
+	}
+	audit(nah.DB, r, noteEvent(models.AuditNoteUpdate, note.ID, note.Title))
+
+	if err := note.LoadTags(nah.DB); err != nil {
+		log.Printf("Failed to load tags of note %d: %v", note.ID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to load note")
+		return
+	}
+
+	w.Header().Set("ETag", noteETag(note))
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (nah *NoteAPIHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := nah.userID(w, r)
+	if !ok {
+		return
+	}
+
+	note, ok := nah.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
+		return
+	}
+
+	if !checkIfMatch(w, r, note) {
+		return
+	}
+
+	if err := note.DeleteNote(nah.DB); err != nil {
+		log.Printf("Failed to delete note %d: %v", note.ID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to delete note")
+		return
+	}
+	audit(nah.DB, r, noteEvent(models.AuditNoteDelete, note.ID, note.Title))
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
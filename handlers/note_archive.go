@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"NotesWebApp/models"
+)
+
+func (nh *NoteHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	pageReq, err := pageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	note := models.Note{}
+	result, err := note.GetNotesByUser(nh.DB, userID, models.NoteFilter{Archived: true}, pageReq)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := models.LoadTagsForNotes(nh.DB, result.Notes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := notesPage{Notes: result.Notes, Sort: pageReq.Sort}
+	if result.NextCursor != "" {
+		page.NextURL = pageURL(r, result.NextCursor)
+	}
+	if pageReq.Cursor != "" {
+		page.FirstURL = pageURL(r, "")
+	}
+
+	tmpl := template.Must(template.New("archive.html").Funcs(noteTemplateFuncs).Funcs(csrfFuncs(w, r)).
+		ParseFiles("templates/archive.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing archive.html:", err)
+		return
+	}
+}
+
+// updateNoteFlag меняет флаг заметки владельца и возвращает пользователя на страницу redirect.
+func (nh *NoteHandler) updateNoteFlag(w http.ResponseWriter, r *http.Request, redirect string,
+	update func(note *models.Note) error,
+) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
+		return
+	}
+
+	if err := update(note); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+func (nh *NoteHandler) PinNote(w http.ResponseWriter, r *http.Request) {
+	nh.updateNoteFlag(w, r, "/notes", func(note *models.Note) error {
+		return note.SetPinned(nh.DB, true)
+	})
+}
+
+func (nh *NoteHandler) UnpinNote(w http.ResponseWriter, r *http.Request) {
+	nh.updateNoteFlag(w, r, "/notes", func(note *models.Note) error {
+		return note.SetPinned(nh.DB, false)
+	})
+}
+
+func (nh *NoteHandler) ArchiveNote(w http.ResponseWriter, r *http.Request) {
+	nh.updateNoteFlag(w, r, "/notes", func(note *models.Note) error {
+		return note.SetArchived(nh.DB, true)
+	})
+}
+
+func (nh *NoteHandler) UnarchiveNote(w http.ResponseWriter, r *http.Request) {
+	nh.updateNoteFlag(w, r, "/notes/archive", func(note *models.Note) error {
+		return note.SetArchived(nh.DB, false)
+	})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"NotesWebApp/models"
+	"NotesWebApp/storage"
+)
+
+const sniffLength = 512
+
+// uploadedFiles возвращает файлы из поля attachments, проверяя их размер до сохранения заметки.
+func uploadedFiles(w http.ResponseWriter, r *http.Request) ([]*multipart.FileHeader, bool) {
+	if r.MultipartForm == nil {
+		return nil, true
+	}
+
+	files := r.MultipartForm.File["attachments"]
+	for _, fh := range files {
+		if fh.Size > models.MaxAttachmentSize {
+			http.Error(w, fh.Filename+": "+models.ErrAttachmentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+	}
+	return files, true
+}
+
+// detectContentType определяет тип по содержимому, а для нераспознанных данных — по расширению.
+func detectContentType(file multipart.File, filename string) (string, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if contentType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(filename)); byExt != "" {
+			contentType = byExt
+		}
+	}
+	return contentType, nil
+}
+
+// saveAttachment кладёт файл в хранилище и создаёт запись о вложении.
+func (nh *NoteHandler) saveAttachment(r *http.Request, noteID int, fh *multipart.FileHeader) error {
+	file, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	attachment := &models.Attachment{
+		NoteID:    noteID,
+		Filename:  models.CleanAttachmentFilename(fh.Filename),
+		SizeBytes: fh.Size,
+	}
+
+	attachment.ContentType, err = detectContentType(file, attachment.Filename)
+	if err != nil {
+		return err
+	}
+
+	attachment.StorageKey, err = models.NewAttachmentKey(noteID)
+	if err != nil {
+		return err
+	}
+
+	if err := nh.Storage.Put(r.Context(), attachment.StorageKey, file, fh.Size, attachment.ContentType); err != nil {
+		return err
+	}
+
+	if err := attachment.CreateAttachment(nh.DB); err != nil {
+		if delErr := nh.Storage.Delete(r.Context(), attachment.StorageKey); delErr != nil {
+			log.Printf("Failed to clean up attachment %s: %v", attachment.StorageKey, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (nh *NoteHandler) saveAttachments(w http.ResponseWriter, r *http.Request, noteID int,
+	files []*multipart.FileHeader,
+) bool {
+	for _, fh := range files {
+		if err := nh.saveAttachment(r, noteID, fh); err != nil {
+			log.Printf("Failed to save attachment %q of note %d: %v", fh.Filename, noteID, err)
+			http.Error(w, "Failed to save attachment", http.StatusInternalServerError)
+			return false
+		}
+	}
+	return true
+}
+
+// noteAttachment загружает вложение из URL, если у пользователя есть нужный доступ к заметке.
+func (nh *NoteHandler) noteAttachment(w http.ResponseWriter, r *http.Request, userID int, required models.Access) (
+	*models.Note, *models.Attachment, bool,
+) {
+	note, _, ok := nh.noteWithAccess(w, r, userID, required)
+	if !ok {
+		return nil, nil, false
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["attachment"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	attachment, err := models.GetAttachment(nh.DB, id, note.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	if attachment == nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	return note, attachment, true
+}
+
+func (nh *NoteHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	_, attachment, ok := nh.noteAttachment(w, r, userID, models.AccessView)
+	if !ok {
+		return
+	}
+
+	content, err := nh.Storage.Open(r.Context(), attachment.StorageKey)
+	if err != nil {
+		log.Printf("Failed to open attachment %d: %v", attachment.ID, err)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Attachment content is missing", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load attachment", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if attachment.IsInline() {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Failed to send attachment %d: %v", attachment.ID, err)
+	}
+}
+
+func (nh *NoteHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, attachment, ok := nh.noteAttachment(w, r, userID, models.AccessEdit)
+	if !ok {
+		return
+	}
+
+	// Сначала удаляется содержимое: при ошибке хранилища запись остаётся и удаление можно повторить.
+	if err := nh.Storage.Delete(r.Context(), attachment.StorageKey); err != nil {
+		log.Printf("Failed to delete attachment %s from storage: %v", attachment.StorageKey, err)
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	if err := attachment.DeleteAttachment(nh.DB); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/notes/edit/"+strconv.Itoa(note.ID), http.StatusSeeOther)
+}
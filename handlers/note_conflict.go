@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"NotesWebApp/models"
+	"NotesWebApp/textdiff"
+)
+
+// conflictPage показывает сохранённую версию заметки рядом с отправленной пользователем
+// и предлагает объединённый текст с маркерами конфликта.
+type conflictPage struct {
+	Current     *models.Note
+	Title       string
+	Content     string
+	Tags        string
+	TitleDiff   []textdiff.Line
+	ContentDiff []textdiff.Line
+	Merged      string
+	IsOwner     bool
+	NotebookID  string
+	Pinned      bool
+	Archived    bool
+}
+
+// renderConflict отвечает 409 и показывает экран конфликта для устаревшей формы редактирования.
+func (nh *NoteHandler) renderConflict(w http.ResponseWriter, r *http.Request, noteID int, access models.Access) {
+	current, err := models.GetNoteByID(nh.DB, noteID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if current == nil {
+		http.Error(w, "Note was deleted while you were editing it", http.StatusConflict)
+		return
+	}
+
+	page := conflictPage{
+		Current:     current,
+		Title:       r.FormValue("title"),
+		Content:     r.FormValue("content"),
+		Tags:        strings.Join(models.ParseTags(r.FormValue("tags")), ", "),
+		TitleDiff:   textdiff.Lines(current.Title, r.FormValue("title")),
+		ContentDiff: textdiff.Lines(current.Content, r.FormValue("content")),
+		IsOwner:     access == models.AccessOwner,
+		NotebookID:  r.FormValue("notebook_id"),
+		Pinned:      r.FormValue("pinned") != "",
+		Archived:    r.FormValue("archived") != "",
+	}
+	page.Merged, _ = textdiff.Merge(current.Content, page.Content, "saved version", "your version")
+
+	tmpl := template.Must(template.New("conflict.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/conflict.html"))
+	w.WriteHeader(http.StatusConflict)
+	err = tmpl.Execute(w, &page)
+	if err != nil {
+		log.Println("Error while executing conflict.html:", err)
+		return
+	}
+}
@@ -1,80 +1,245 @@
 package handlers
 
 import (
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"NotesWebApp/markdown"
 	"NotesWebApp/models"
-	"github.com/gorilla/mux"
+	"NotesWebApp/storage"
 	"github.com/jmoiron/sqlx"
 )
 
 type NoteHandler struct {
-	DB *sqlx.DB
+	DB      *sqlx.DB
+	Storage storage.Storage
+	// BaseURL — внешний адрес приложения (APP_URL) для публичных ссылок.
+	BaseURL string
 }
 
-func NewNoteHandler(db *sqlx.DB) *NoteHandler {
-	return &NoteHandler{DB: db}
+var noteTemplateFuncs = template.FuncMap{
+	"markdown": renderMarkdown,
 }
 
-func (nh *NoteHandler) GetNotes(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionName)
+// renderMarkdown используется в шаблонах; при ошибке показывает исходный текст как есть.
+func renderMarkdown(source string) template.HTML {
+	out, err := markdown.Render(source)
 	if err != nil {
-		http.Error(w, "Failed to get session", http.StatusBadRequest)
-		return
+		log.Println("Error while rendering markdown:", err)
+		return template.HTML("<pre>" + template.HTMLEscapeString(source) + "</pre>") //nolint:gosec // текст экранирован
 	}
+	return out
+}
+
+type notesPage struct {
+	Notes          []models.Note
+	Shared         []models.SharedNote
+	TagCloud       []models.Tag
+	ActiveTag      string
+	Notebooks      []models.Notebook
+	ActiveNotebook *models.Notebook
+	Sort           models.NoteSort
+	NextURL        string
+	FirstURL       string
+}
 
-	userID, ok := session.Values["userID"].(int)
+func NewNoteHandler(db *sqlx.DB, store storage.Storage, baseURL string) *NoteHandler {
+	return &NoteHandler{DB: db, Storage: store, BaseURL: baseURL}
+}
+
+func (nh *NoteHandler) GetNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
 	if !ok {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
 	note := models.Note{}
+	filter := models.NoteFilter{Tag: strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))}
+
+	pageReq, err := pageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notebooks, err := models.GetNotebooksByUser(nh.DB, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var activeNotebook *models.Notebook
+	if raw := r.URL.Query().Get("notebook"); raw != "" {
+		id, _ := strconv.Atoi(raw)
+		for i := range notebooks {
+			if notebooks[i].ID == id {
+				activeNotebook = &notebooks[i]
+			}
+		}
+		if activeNotebook == nil {
+			http.Error(w, "Notebook not found", http.StatusNotFound)
+			return
+		}
+		filter.NotebookID = activeNotebook.ID
+	}
+
+	result, err := note.GetNotesByUser(nh.DB, userID, filter, pageReq)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := models.LoadTagsForNotes(nh.DB, result.Notes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	notes, err := note.GetNotesByUser(nh.DB, userID)
+	tags, err := models.GetTagCloud(nh.DB, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := notesPage{
+		Notes:          result.Notes,
+		TagCloud:       tags,
+		ActiveTag:      filter.Tag,
+		Notebooks:      notebooks,
+		ActiveNotebook: activeNotebook,
+		Sort:           pageReq.Sort,
+	}
+
+	if result.NextCursor != "" {
+		page.NextURL = pageURL(r, result.NextCursor)
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/index.html"))
-	err = tmpl.Execute(w, notes)
+	// Заметки других пользователей показываются только на первой странице.
+	if pageReq.Cursor == "" {
+		page.Shared, err = models.GetNotesSharedWithUser(nh.DB, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		page.FirstURL = pageURL(r, "")
+	}
+
+	tmpl := template.Must(template.New("index.html").Funcs(noteTemplateFuncs).Funcs(csrfFuncs(w, r)).
+		ParseFiles("templates/index.html"))
+	err = tmpl.Execute(w, page)
 	if err != nil {
 		log.Println("Error while executing index.html:", err)
 		return
 	}
 }
 
-func (nh *NoteHandler) CreateNoteForm(w http.ResponseWriter, _ *http.Request) {
-	tmpl := template.Must(template.ParseFiles("templates/create.html"))
-	err := tmpl.Execute(w, nil)
+type searchPage struct {
+	Query   string
+	Results []models.SearchResult
+}
+
+func (nh *NoteHandler) SearchNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	page := searchPage{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+
+	if page.Query != "" {
+		results, err := models.SearchNotes(nh.DB, userID, page.Query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Results = results
+	}
+
+	tmpl := template.Must(template.ParseFiles("templates/search.html"))
+	err := tmpl.Execute(w, page)
 	if err != nil {
-		log.Println("Error while executing create.html:", err)
+		log.Println("Error while executing search.html:", err)
 		return
 	}
 }
 
-func (nh *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionName)
+type createPage struct {
+	Notebooks        []models.Notebook
+	SelectedNotebook int
+}
+
+func (nh *NoteHandler) CreateNoteForm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notebooks, err := models.GetNotebooksByUser(nh.DB, userID)
 	if err != nil {
-		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	userID, ok := session.Values["userID"].(int)
+
+	page := createPage{Notebooks: notebooks}
+	page.SelectedNotebook, _ = strconv.Atoi(r.URL.Query().Get("notebook"))
+
+	tmpl := template.Must(template.New("create.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/create.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing create.html:", err)
+		return
+	}
+}
+
+func (nh *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
 	if !ok {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
+	if !parseForm(w, r) {
+		return
+	}
+
 	title := r.FormValue("title")
 	content := r.FormValue("content")
 
+	tags := models.ParseTags(r.FormValue("tags"))
+	if err := models.ValidateTags(tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files, ok := uploadedFiles(w, r)
+	if !ok {
+		return
+	}
+
+	notebookID, ok := nh.notebookFromForm(w, r, userID)
+	if !ok {
+		return
+	}
+
 	note := &models.Note{
-		Title:   title,
-		Content: content,
-		UserID:  userID,
+		Title:      title,
+		Content:    content,
+		UserID:     userID,
+		NotebookID: notebookID,
+		Pinned:     r.FormValue("pinned") != "",
+		Archived:   r.FormValue("archived") != "",
+		Tags:       tags,
 	}
 
 	if err := note.CreateNote(nh.DB); err != nil {
@@ -82,130 +247,228 @@ func (nh *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit(nh.DB, r, noteEvent(models.AuditNoteCreate, note.ID, note.Title))
+
+	if !nh.saveAttachments(w, r, note.ID, files) {
+		return
+	}
+
 	http.Redirect(w, r, "/notes", http.StatusFound)
 }
 
-func (nh *NoteHandler) EditNoteForm(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionName)
+type editPage struct {
+	Note        *models.Note
+	Access      models.Access
+	Notebooks   []models.Notebook
+	Attachments []models.Attachment
+	Shares      []models.NoteShare
+	Links       []models.PublicLink
+	NewLinkURL  string
+}
+
+// notebookFromForm читает блокнот из формы; блокнот должен принадлежать пользователю.
+func (nh *NoteHandler) notebookFromForm(w http.ResponseWriter, r *http.Request, userID int) (int, bool) {
+	notebookID, err := ownedNotebookID(nh.DB, userID, r.FormValue("notebook_id"))
 	if err != nil {
-		http.Error(w, "Failed to get session", http.StatusBadRequest)
-		return
+		if errors.Is(err, errInvalidNotebook) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return 0, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
 	}
+	return notebookID, true
+}
+
+func (ep *editPage) IsOwner() bool {
+	return ep.Access == models.AccessOwner
+}
+
+func (ep *editPage) CanEdit() bool {
+	return ep.Access >= models.AccessEdit
+}
 
-	userID, ok := session.Values["userID"].(int)
+func (nh *NoteHandler) ViewNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
 	if !ok {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["id"])
+	note, access, ok := nh.noteWithAccess(w, r, userID, models.AccessView)
+	if !ok {
+		return
+	}
 
-	note, err := models.GetNoteByID(nh.DB, id)
-	if err != nil {
+	if err := note.LoadTags(nh.DB); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("полученная заметка GetNoteByID: %+v", note)
-
-	if note.UserID != userID {
-		log.Printf("User %d tried to edit note %d belonging to user %d", userID, note.ID, note.UserID)
-		http.Error(w, "You do not have permission to edit this note", http.StatusForbidden)
+	attachments, err := models.GetAttachmentsByNote(nh.DB, note.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/edit.html"))
-	err = tmpl.Execute(w, note)
+	tmpl := template.Must(template.New("view.html").Funcs(noteTemplateFuncs).ParseFiles("templates/view.html"))
+	err = tmpl.Execute(w, &editPage{Note: note, Access: access, Attachments: attachments})
 	if err != nil {
-		log.Println("Error while executing edit.html:", err)
+		log.Println("Error while executing view.html:", err)
 		return
 	}
 }
 
-func (nh *NoteHandler) EditNote(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionName)
-	if err != nil {
-		http.Error(w, "Failed to get session", http.StatusBadRequest)
+func (nh *NoteHandler) EditNoteForm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	userID, ok := session.Values["userID"].(int)
+	note, access, ok := nh.noteWithAccess(w, r, userID, models.AccessEdit)
 	if !ok {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+	if err := note.LoadTags(nh.DB); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	note, err := models.GetNoteByID(nh.DB, id)
+	attachments, err := models.GetAttachmentsByNote(nh.DB, note.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := editPage{Note: note, Access: access, Attachments: attachments}
+
+	if access == models.AccessOwner {
+		notebooks, err := models.GetNotebooksByUser(nh.DB, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Notebooks = notebooks
+
+		shares, err := models.GetNoteShares(nh.DB, note.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Shares = shares
+
+		links, err := models.GetPublicLinksByNote(nh.DB, note.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page.Links = links
+		page.NewLinkURL = popFlash(w, r, publicLinkFlashKey)
+	}
+
+	tmpl := template.Must(template.New("edit.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/edit.html"))
+	err = tmpl.Execute(w, &page)
+	if err != nil {
+		log.Println("Error while executing edit.html:", err)
+		return
+	}
+}
+
+func (nh *NoteHandler) EditNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
 	}
 
-	if note == nil {
-		http.Error(w, "Note not found", http.StatusNotFound)
+	note, access, ok := nh.noteWithAccess(w, r, userID, models.AccessEdit)
+	if !ok {
 		return
 	}
 
-	if note.UserID != userID {
-		log.Printf("User %d tried to edit note %d belonging to user %d", userID, note.ID, note.UserID)
-		http.Error(w, "You do not have permission to edit this note", http.StatusForbidden)
+	if !parseForm(w, r) {
 		return
 	}
 
 	title := r.FormValue("title")
 	content := r.FormValue("content")
 
-	note.Title = title
-	note.Content = content
-
-	if err := note.UpdateNote(nh.DB); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	tags := models.ParseTags(r.FormValue("tags"))
+	if err := models.ValidateTags(tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	http.Redirect(w, r, "/notes", http.StatusFound)
-}
+	files, ok := uploadedFiles(w, r)
+	if !ok {
+		return
+	}
 
-func (nh *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
-	session, err := store.Get(r, sessionName)
+	version, err := strconv.Atoi(r.FormValue("version"))
 	if err != nil {
-		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		http.Error(w, "Missing note version", http.StatusBadRequest)
 		return
 	}
 
-	userID, ok := session.Values["userID"].(int)
-	if !ok {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	// Блокноты есть только у владельца, поэтому перемещать заметку может только он.
+	notebookID := 0
+	if access == models.AccessOwner {
+		if notebookID, ok = nh.notebookFromForm(w, r, userID); !ok {
+			return
+		}
+	}
+
+	// Вложения не конфликтуют с правками текста, поэтому сохраняются в любом случае.
+	if !nh.saveAttachments(w, r, note.ID, files) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+	// Форма открыта для устаревшей версии: вместо перезаписи показываем экран конфликта.
+	if version != note.Version {
+		nh.renderConflict(w, r, note.ID, access)
 		return
 	}
 
-	note, err := models.GetNoteByID(nh.DB, id)
-	if err != nil {
+	note.Title = title
+	note.Content = content
+	if access == models.AccessOwner {
+		applyOwnerSettings(r, note, notebookID)
+	}
+
+	if err := note.UpdateNote(nh.DB, userID, tags); err != nil {
+		if errors.Is(err, models.ErrVersionConflict) {
+			nh.renderConflict(w, r, note.ID, access)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	audit(nh.DB, r, noteEvent(models.AuditNoteUpdate, note.ID, note.Title))
 
-	if note == nil {
-		http.Error(w, "Note not found", http.StatusNotFound)
+	http.Redirect(w, r, "/notes", http.StatusFound)
+}
+
+// applyOwnerSettings переносит в заметку блокнот и флаги закрепления и архива из формы владельца;
+// сохраняются они вместе с текстом.
+func applyOwnerSettings(r *http.Request, note *models.Note, notebookID int) {
+	if notebookID != 0 {
+		note.NotebookID = notebookID
+	}
+	note.Pinned = r.FormValue("pinned") != ""
+	note.Archived = r.FormValue("archived") != ""
+}
+
+func (nh *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	if note.UserID != userID {
-		log.Printf("User %d tried to edit note %d belonging to user %d", userID, note.ID, note.UserID)
-		http.Error(w, "You do not have permission to edit this note", http.StatusForbidden)
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
 		return
 	}
 
@@ -213,6 +476,7 @@ func (nh *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	audit(nh.DB, r, noteEvent(models.AuditNoteDelete, note.ID, note.Title))
 
 	http.Redirect(w, r, "/notes", http.StatusSeeOther)
 }
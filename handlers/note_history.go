@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"NotesWebApp/models"
+	"NotesWebApp/textdiff"
+)
+
+type historyPage struct {
+	Note        *models.Note
+	Revisions   []models.NoteRevision
+	Selected    *models.NoteRevision
+	TitleDiff   []textdiff.Line
+	ContentDiff []textdiff.Line
+	Changed     bool
+	CanEdit     bool
+}
+
+func (nh *NoteHandler) NoteHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, access, ok := nh.noteWithAccess(w, r, userID, models.AccessView)
+	if !ok {
+		return
+	}
+
+	revisions, err := models.GetNoteRevisions(nh.DB, note.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := historyPage{Note: note, Revisions: revisions, CanEdit: access >= models.AccessEdit}
+
+	// По умолчанию сравниваем с предыдущей ревизией: самая новая совпадает с текущей версией.
+	if rev := r.URL.Query().Get("rev"); rev != "" {
+		revisionID, err := strconv.Atoi(rev)
+		if err != nil {
+			http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+			return
+		}
+		for i := range revisions {
+			if revisions[i].ID == revisionID {
+				page.Selected = &revisions[i]
+			}
+		}
+		if page.Selected == nil {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+	} else if len(revisions) > 1 {
+		page.Selected = &revisions[1]
+	} else if len(revisions) == 1 {
+		page.Selected = &revisions[0]
+	}
+
+	if page.Selected != nil {
+		page.TitleDiff = textdiff.Lines(page.Selected.Title, note.Title)
+		page.ContentDiff = textdiff.Lines(page.Selected.Content, note.Content)
+		page.Changed = textdiff.Changed(page.TitleDiff) || textdiff.Changed(page.ContentDiff)
+	}
+
+	tmpl := template.Must(template.New("history.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/history.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing history.html:", err)
+		return
+	}
+}
+
+// RestoreRevision возвращает заметку к выбранной ревизии, записывая это как новую ревизию.
+func (nh *NoteHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessEdit)
+	if !ok {
+		return
+	}
+
+	revisionID, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := models.GetNoteRevision(nh.DB, note.ID, revisionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if revision == nil {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+
+	note.Title = revision.Title
+	note.Content = revision.Content
+
+	if err := note.UpdateNote(nh.DB, userID, nil); err != nil {
+		if errors.Is(err, models.ErrVersionConflict) {
+			http.Error(w, "Note was changed while restoring, please try again", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d restored note %d to revision %d", userID, note.ID, revision.ID)
+	audit(nh.DB, r, noteEvent(models.AuditNoteUpdate, note.ID, "restored revision "+strconv.Itoa(revision.ID)))
+	http.Redirect(w, r, "/notes/history/"+strconv.Itoa(note.ID), http.StatusSeeOther)
+}
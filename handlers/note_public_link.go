@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"NotesWebApp/models"
+)
+
+const (
+	publicLinkFlashKey        = "public_link"
+	maxPublicLinkLifetimeDays = 3650
+)
+
+func (nh *NoteHandler) CreatePublicLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
+		return
+	}
+
+	link := &models.PublicLink{NoteID: note.ID}
+
+	if days := r.FormValue("expires_in_days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 || n > maxPublicLinkLifetimeDays {
+			http.Error(w, "Invalid link lifetime", http.StatusBadRequest)
+			return
+		}
+		expiresAt := time.Now().AddDate(0, 0, n)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := link.SetPassword(r.FormValue("password")); err != nil {
+		http.Error(w, "Failed to process password", http.StatusInternalServerError)
+		return
+	}
+
+	plain, err := models.GeneratePublicLinkToken()
+	if err != nil {
+		http.Error(w, "Failed to generate link", http.StatusInternalServerError)
+		return
+	}
+
+	if err := link.CreatePublicLink(nh.DB, plain); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Ссылка хранится только в виде хеша, поэтому показываем её один раз после редиректа.
+	if err := addFlash(w, r, publicLinkFlashKey, nh.BaseURL+"/p/"+plain); err != nil {
+		log.Println("Can't save session:", err)
+	}
+
+	log.Printf("User %d created public link %d for note %d", userID, link.ID, note.ID)
+	audit(nh.DB, r, noteEvent(models.AuditLinkCreate, note.ID, "link "+strconv.Itoa(link.ID)))
+	http.Redirect(w, r, "/notes/edit/"+strconv.Itoa(note.ID), http.StatusSeeOther)
+}
+
+func (nh *NoteHandler) RevokePublicLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
+		return
+	}
+
+	linkID, err := strconv.Atoi(mux.Vars(r)["link"])
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := models.DeletePublicLink(nh.DB, linkID, note.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("User %d revoked public link %d of note %d", userID, linkID, note.ID)
+	audit(nh.DB, r, noteEvent(models.AuditLinkRevoke, note.ID, "link "+strconv.Itoa(linkID)))
+	http.Redirect(w, r, "/notes/edit/"+strconv.Itoa(note.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"NotesWebApp/models"
+)
+
+func (nh *NoteHandler) ShareNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	permission := r.FormValue("permission")
+
+	if err := models.ValidatePermission(permission); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := models.GetUserByEmail(nh.DB, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "No registered user with this email", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if user.ID == userID {
+		http.Error(w, "You already own this note", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ShareNote(nh.DB, note.ID, user.ID, permission); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d shared note %d with user %d as %s", userID, note.ID, user.ID, permission)
+	audit(nh.DB, r, noteEvent(models.AuditNoteShare, note.ID, "with "+user.Email+" as "+permission))
+	http.Redirect(w, r, "/notes/edit/"+strconv.Itoa(note.ID), http.StatusSeeOther)
+}
+
+func (nh *NoteHandler) UnshareNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, _, ok := nh.noteWithAccess(w, r, userID, models.AccessOwner)
+	if !ok {
+		return
+	}
+
+	shareUserID, err := strconv.Atoi(mux.Vars(r)["user"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.UnshareNote(nh.DB, note.ID, shareUserID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d stopped sharing note %d with user %d", userID, note.ID, shareUserID)
+	audit(nh.DB, r, noteEvent(models.AuditNoteUnshare, note.ID, "with user "+strconv.Itoa(shareUserID)))
+	http.Redirect(w, r, "/notes/edit/"+strconv.Itoa(note.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"NotesWebApp/models"
+)
+
+// trashedNote загружает заметку из корзины и проверяет, что она принадлежит пользователю.
+func (nh *NoteHandler) trashedNote(w http.ResponseWriter, r *http.Request, userID int) (*models.Note, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	note, err := models.GetTrashedNoteByID(nh.DB, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if note == nil {
+		http.Error(w, "Note not found in trash", http.StatusNotFound)
+		return nil, false
+	}
+
+	if note.UserID != userID {
+		log.Printf("User %d tried to access trashed note %d belonging to user %d", userID, note.ID, note.UserID)
+		http.Error(w, "You do not have permission to access this note", http.StatusForbidden)
+		return nil, false
+	}
+
+	return note, true
+}
+
+func (nh *NoteHandler) Trash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notes, err := models.GetTrashedNotesByUser(nh.DB, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tmpl := template.Must(template.New("trash.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/trash.html"))
+	err = tmpl.Execute(w, notes)
+	if err != nil {
+		log.Println("Error while executing trash.html:", err)
+		return
+	}
+}
+
+func (nh *NoteHandler) RestoreNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, ok := nh.trashedNote(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := note.RestoreNote(nh.DB); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	audit(nh.DB, r, noteEvent(models.AuditNoteRestore, note.ID, note.Title))
+
+	http.Redirect(w, r, "/notes/trash", http.StatusSeeOther)
+}
+
+func (nh *NoteHandler) PurgeNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	note, ok := nh.trashedNote(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := note.PurgeNote(nh.DB); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d permanently deleted note %d", userID, note.ID)
+	audit(nh.DB, r, noteEvent(models.AuditNotePurge, note.ID, note.Title))
+	http.Redirect(w, r, "/notes/trash", http.StatusSeeOther)
+}
+
+func (nh *NoteHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	purged, err := models.EmptyTrash(nh.DB, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d emptied trash, %d notes deleted", userID, purged)
+	audit(nh.DB, r, models.AuditEvent{Action: models.AuditTrashEmpty, Details: strconv.FormatInt(purged, 10) + " notes"})
+	http.Redirect(w, r, "/notes/trash", http.StatusSeeOther)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+var errInvalidNotebook = errors.New("notebook not found")
+
+type NotebookHandler struct {
+	DB *sqlx.DB
+}
+
+func NewNotebookHandler(db *sqlx.DB) *NotebookHandler {
+	return &NotebookHandler{DB: db}
+}
+
+type notebooksPage struct {
+	Notebooks []models.Notebook
+	Error     string
+}
+
+// ownedNotebookID разбирает идентификатор блокнота из формы и проверяет, что он принадлежит пользователю.
+// Пустое значение означает блокнот по умолчанию и возвращается как 0.
+func ownedNotebookID(db *sqlx.DB, userID int, raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, errInvalidNotebook
+	}
+
+	notebook, err := models.GetNotebook(db, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	if notebook == nil {
+		return 0, errInvalidNotebook
+	}
+	return notebook.ID, nil
+}
+
+func notebookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrEmptyNotebookName), errors.Is(err, models.ErrNotebookNameTooLong),
+		errors.Is(err, models.ErrDefaultNotebookDelete), errors.Is(err, errInvalidNotebook):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrDuplicateNotebookName):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func (nbh *NotebookHandler) render(w http.ResponseWriter, r *http.Request, userID, status int, page notebooksPage) {
+	notebooks, err := models.GetNotebooksByUser(nbh.DB, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page.Notebooks = notebooks
+
+	tmpl := template.Must(template.New("notebooks.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/notebooks.html"))
+	w.WriteHeader(status)
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing notebooks.html:", err)
+		return
+	}
+}
+
+// fail показывает страницу блокнотов с сообщением об ошибке.
+func (nbh *NotebookHandler) fail(w http.ResponseWriter, r *http.Request, userID int, err error) {
+	status := notebookErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("Notebook operation failed for user %d: %v", userID, err)
+		http.Error(w, "Internal server error", status)
+		return
+	}
+	nbh.render(w, r, userID, status, notebooksPage{Error: err.Error()})
+}
+
+func (nbh *NotebookHandler) ListNotebooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	nbh.render(w, r, userID, http.StatusOK, notebooksPage{})
+}
+
+func (nbh *NotebookHandler) CreateNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notebook := &models.Notebook{
+		UserID: userID,
+		Name:   strings.TrimSpace(r.FormValue("name")),
+	}
+
+	if err := models.ValidateNotebookName(notebook.Name); err != nil {
+		nbh.fail(w, r, userID, err)
+		return
+	}
+
+	if err := notebook.CreateNotebook(nbh.DB); err != nil {
+		nbh.fail(w, r, userID, err)
+		return
+	}
+
+	http.Redirect(w, r, "/notebooks", http.StatusSeeOther)
+}
+
+// notebook загружает блокнот текущего пользователя из URL.
+func (nbh *NotebookHandler) notebook(w http.ResponseWriter, r *http.Request, userID int) (*models.Notebook, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid notebook ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	notebook, err := models.GetNotebook(nbh.DB, id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if notebook == nil {
+		http.Error(w, "Notebook not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return notebook, true
+}
+
+func (nbh *NotebookHandler) RenameNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notebook, ok := nbh.notebook(w, r, userID)
+	if !ok {
+		return
+	}
+
+	notebook.Name = strings.TrimSpace(r.FormValue("name"))
+
+	if err := models.ValidateNotebookName(notebook.Name); err != nil {
+		nbh.fail(w, r, userID, err)
+		return
+	}
+
+	if err := notebook.RenameNotebook(nbh.DB); err != nil {
+		nbh.fail(w, r, userID, err)
+		return
+	}
+
+	http.Redirect(w, r, "/notebooks", http.StatusSeeOther)
+}
+
+func (nbh *NotebookHandler) DeleteNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notebook, ok := nbh.notebook(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := notebook.DeleteNotebook(nbh.DB); err != nil {
+		nbh.fail(w, r, userID, err)
+		return
+	}
+
+	log.Printf("User %d deleted notebook %d, notes moved to the default notebook", userID, notebook.ID)
+	http.Redirect(w, r, "/notebooks", http.StatusSeeOther)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"NotesWebApp/models"
+)
+
+// pageRequest читает параметры sort, limit и cursor из строки запроса.
+func pageRequest(r *http.Request) (models.PageRequest, error) {
+	query := r.URL.Query()
+
+	sort, err := models.ParseNoteSort(query.Get("sort"))
+	if err != nil {
+		return models.PageRequest{}, err
+	}
+
+	page := models.PageRequest{Sort: sort, Cursor: query.Get("cursor")}
+
+	if raw := query.Get("limit"); raw != "" {
+		page.Limit, err = strconv.Atoi(raw)
+		if err != nil || page.Limit < 1 || page.Limit > models.MaxPageSize {
+			return models.PageRequest{}, models.ErrInvalidPageSize
+		}
+	}
+	return page, nil
+}
+
+// pageURL возвращает адрес текущего списка с другим курсором; пустой курсор ведёт на первую страницу.
+func pageURL(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	if cursor == "" {
+		query.Del("cursor")
+	} else {
+		query.Set("cursor", cursor)
+	}
+
+	if len(query) == 0 {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
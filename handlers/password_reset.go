@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+
+	"NotesWebApp/mail"
+	"NotesWebApp/models"
+	"NotesWebApp/validation"
+)
+
+var (
+	// passwordResetThrottle ограничивает письма со ссылкой сброса на один адрес: после каждого запроса
+	// следующий принимается не раньше чем через удваивающуюся паузу.
+	passwordResetThrottle = models.LoginThrottle{
+		Window:          24 * time.Hour,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAfter:    10,
+		LockoutDuration: 24 * time.Hour,
+	}
+	// ipPasswordResetThrottle ограничивает запросы сброса для разных адресов с одного IP.
+	ipPasswordResetThrottle = models.LoginThrottle{
+		Window:          time.Hour,
+		FreeAttempts:    10,
+		BaseDelay:       time.Minute,
+		MaxDelay:        15 * time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: time.Hour,
+	}
+)
+
+type forgotPasswordPage struct {
+	Sent  bool
+	Error string
+}
+
+type resetPasswordPage struct {
+	Token string
+	Error string
+}
+
+func (ah *AuthHandler) ForgotPasswordForm(w http.ResponseWriter, r *http.Request) {
+	ah.renderForgotPassword(w, r, http.StatusOK, forgotPasswordPage{})
+}
+
+func (ah *AuthHandler) renderForgotPassword(
+	w http.ResponseWriter, r *http.Request, status int, page forgotPasswordPage,
+) {
+	tmpl := template.Must(template.New("forgot_password.html").Funcs(csrfFuncs(w, r)).
+		ParseFiles("templates/forgot_password.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing forgot_password.html:", err)
+		return
+	}
+}
+
+// ForgotPassword отправляет ссылку для сброса пароля. Ответ одинаков независимо от того,
+// зарегистрирован ли адрес, а письмо уходит в фоне, чтобы не выдавать это и по времени ответа.
+// Запросы для одного адреса и с одного IP ограничиваются, чтобы через форму нельзя было завалить
+// почтовый ящик письмами; учитываются и запросы для незарегистрированных адресов.
+func (ah *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.FormValue("email"))
+
+	checks := []throttleCheck{
+		{passwordResetThrottle, models.ThrottlePasswordReset, throttleKey(email)},
+		{ipPasswordResetThrottle, models.ThrottlePasswordResetIP, clientIP(r)},
+	}
+	wait, err := throttleWait(ah.DB, checks...)
+	if err != nil {
+		log.Printf("Failed to check password reset requests: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		ah.renderForgotPassword(w, r, http.StatusTooManyRequests, forgotPasswordPage{
+			Error: "Too many password reset requests. Try again in " + retryAfter(w, wait),
+		})
+		return
+	}
+	recordThrottle(ah.DB, checks...)
+
+	user, err := models.GetUserByEmail(ah.DB, email)
+	switch {
+	case err == nil:
+		ah.sendPasswordReset(user)
+	case !errors.Is(err, sql.ErrNoRows):
+		log.Printf("Failed to look up user for password reset: %v", err)
+	}
+
+	ah.renderForgotPassword(w, r, http.StatusOK, forgotPasswordPage{Sent: true})
+}
+
+// passwordResetURL создаёт для пользователя одноразовую ссылку на страницу выбора нового пароля.
+func (ah *AuthHandler) passwordResetURL(user *models.User) (string, error) {
+	plain, err := models.GeneratePasswordResetToken()
+	if err != nil {
+		return "", err
+	}
+
+	reset := &models.PasswordReset{UserID: user.ID, ExpiresAt: time.Now().Add(models.PasswordResetLifetime)}
+	if err := reset.CreatePasswordReset(ah.DB, plain); err != nil {
+		return "", err
+	}
+
+	log.Printf("Password reset %d requested for user %d", reset.ID, user.ID)
+	return ah.BaseURL + "/password/reset/" + plain, nil
+}
+
+func (ah *AuthHandler) sendPasswordReset(user *models.User) {
+	link, err := ah.passwordResetURL(user)
+	if err != nil {
+		log.Printf("Failed to create password reset for user %d: %v", user.ID, err)
+		return
+	}
+
+	ah.sendMail(user.ID, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Notes password",
+		Body: "Someone asked to reset the password for your Notes account.\n\n" +
+			"To choose a new password, open this link within an hour:\n" +
+			link + "\n\n" +
+			"If it wasn't you, ignore this email; your password will not change.\n",
+	})
+}
+
+func (ah *AuthHandler) renderResetPassword(w http.ResponseWriter, r *http.Request, status int, page resetPasswordPage) {
+	// Токен находится в URL, поэтому не передаём его дальше через Referer.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+
+	tmpl := template.Must(template.New("reset_password.html").Funcs(csrfFuncs(w, r)).
+		ParseFiles("templates/reset_password.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing reset_password.html:", err)
+		return
+	}
+}
+
+// passwordReset находит действующую ссылку сброса по токену из URL.
+func (ah *AuthHandler) passwordReset(w http.ResponseWriter, r *http.Request) (*models.PasswordReset, bool) {
+	reset, err := models.GetPasswordResetByToken(ah.DB, mux.Vars(r)["token"])
+	if err != nil {
+		log.Printf("Failed to get password reset: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if reset == nil {
+		ah.renderResetPassword(w, r, http.StatusGone, resetPasswordPage{Error: models.ErrPasswordResetInvalid.Error()})
+		return nil, false
+	}
+
+	return reset, true
+}
+
+func (ah *AuthHandler) ResetPasswordForm(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ah.passwordReset(w, r); !ok {
+		return
+	}
+
+	ah.renderResetPassword(w, r, http.StatusOK, resetPasswordPage{Token: mux.Vars(r)["token"]})
+}
+
+func (ah *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	reset, ok := ah.passwordReset(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := models.GetUserByID(ah.DB, reset.UserID)
+	if err != nil || user == nil {
+		log.Printf("Failed to get user %d for password reset: %v", reset.UserID, err)
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	token := mux.Vars(r)["token"]
+	password := r.FormValue("password")
+
+	if err := ah.PasswordPolicy.Check(password, user.Email); err != nil {
+		page := resetPasswordPage{Token: token, Error: validation.Message(err)}
+		ah.renderResetPassword(w, r, http.StatusBadRequest, page)
+		return
+	}
+	if password != r.FormValue("confirm_password") {
+		ah.renderResetPassword(w, r, http.StatusBadRequest, resetPasswordPage{Token: token, Error: "Passwords do not match"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Failed to hash password for user %d: %v", reset.UserID, err)
+
+		http.Error(w, "Internal server error: failed to process password", http.StatusInternalServerError)
+		return
+	}
+
+	err = reset.ResetPassword(ah.DB, string(hashedPassword))
+	if errors.Is(err, models.ErrPasswordResetInvalid) {
+		ah.renderResetPassword(w, r, http.StatusGone, resetPasswordPage{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to reset password for user %d: %v", reset.UserID, err)
+
+		http.Error(w, "Internal server error: failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d reset their password, all sessions and API tokens were revoked", reset.UserID)
+	http.Redirect(w, r, "/login?reset=1", http.StatusSeeOther)
+}
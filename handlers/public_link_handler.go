@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+var (
+	// linkPasswordThrottle ограничивает подбор пароля к одной ссылке с любых адресов.
+	linkPasswordThrottle = models.LoginThrottle{
+		Window:          24 * time.Hour,
+		FreeAttempts:    5,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    20,
+		LockoutDuration: time.Hour,
+	}
+	// ipLinkPasswordThrottle ограничивает перебор паролей разных ссылок с одного адреса.
+	ipLinkPasswordThrottle = models.LoginThrottle{
+		Window:          time.Hour,
+		FreeAttempts:    20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    100,
+		LockoutDuration: time.Hour,
+	}
+)
+
+// PublicLinkHandler отдаёт заметки по публичным ссылкам без входа в систему.
+type PublicLinkHandler struct {
+	DB *sqlx.DB
+}
+
+func NewPublicLinkHandler(db *sqlx.DB) *PublicLinkHandler {
+	return &PublicLinkHandler{DB: db}
+}
+
+type publicNotePage struct {
+	Token         string
+	NeedsPassword bool
+	Error         string
+	Note          *models.Note
+}
+
+func (ph *PublicLinkHandler) render(w http.ResponseWriter, r *http.Request, status int, page publicNotePage) {
+	// Токен находится в URL, поэтому не передаём его дальше через Referer и не даём индексировать.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Токен CSRF нужен только для формы пароля, обычный просмотр не выставляет для него cookie.
+	csrf := template.FuncMap{"csrfField": func() template.HTML { return "" }}
+	if page.NeedsPassword {
+		csrf = csrfFuncs(w, r)
+	}
+	tmpl := template.Must(template.New("public.html").Funcs(noteTemplateFuncs).Funcs(csrf).
+		ParseFiles("templates/public.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing public.html:", err)
+		return
+	}
+}
+
+// resolve находит действующую ссылку и заметку по токену из URL.
+func (ph *PublicLinkHandler) resolve(w http.ResponseWriter, r *http.Request) (*models.PublicLink, *models.Note, bool) {
+	token := mux.Vars(r)["token"]
+
+	link, err := models.GetPublicLinkByToken(ph.DB, token)
+	if err != nil {
+		log.Printf("Failed to get public link: %v", err)
+		http.Error(w, "Failed to load note", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	if link == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if link.IsExpired(time.Now()) {
+		http.Error(w, "This link has expired", http.StatusGone)
+		return nil, nil, false
+	}
+
+	note, err := models.GetNoteByID(ph.DB, link.NoteID)
+	if err != nil {
+		log.Printf("Failed to get note %d: %v", link.NoteID, err)
+		http.Error(w, "Failed to load note", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	if note == nil {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	return link, note, true
+}
+
+func (ph *PublicLinkHandler) showNote(w http.ResponseWriter, r *http.Request,
+	link *models.PublicLink, note *models.Note, token string,
+) {
+	if err := link.IncrementViews(ph.DB); err != nil {
+		log.Printf("Failed to count view of public link %d: %v", link.ID, err)
+	}
+	ph.render(w, r, http.StatusOK, publicNotePage{Token: token, Note: note})
+}
+
+func (ph *PublicLinkHandler) ShowNote(w http.ResponseWriter, r *http.Request) {
+	link, note, ok := ph.resolve(w, r)
+	if !ok {
+		return
+	}
+
+	token := mux.Vars(r)["token"]
+
+	if link.HasPassword() {
+		ph.render(w, r, http.StatusOK, publicNotePage{Token: token, NeedsPassword: true})
+		return
+	}
+
+	ph.showNote(w, r, link, note, token)
+}
+
+func (ph *PublicLinkHandler) UnlockNote(w http.ResponseWriter, r *http.Request) {
+	link, note, ok := ph.resolve(w, r)
+	if !ok {
+		return
+	}
+
+	token := mux.Vars(r)["token"]
+	checks := []throttleCheck{
+		{linkPasswordThrottle, models.ThrottleLinkPassword, strconv.Itoa(link.ID)},
+		{ipLinkPasswordThrottle, models.ThrottleLinkPasswordIP, clientIP(r)},
+	}
+
+	wait, err := throttleWait(ph.DB, checks...)
+	if err != nil {
+		log.Printf("Failed to check public link password attempts: %v", err)
+		http.Error(w, "Failed to load note", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		ph.render(w, r, http.StatusTooManyRequests, publicNotePage{
+			Token: token, NeedsPassword: true, Error: "Too many wrong passwords. Try again in " + retryAfter(w, wait),
+		})
+		return
+	}
+
+	if !link.CheckPassword(r.FormValue("password")) {
+		recordThrottle(ph.DB, checks...)
+		ph.render(w, r, http.StatusUnauthorized, publicNotePage{Token: token, NeedsPassword: true, Error: "Wrong password"})
+		return
+	}
+
+	ph.showNote(w, r, link, note, token)
+}
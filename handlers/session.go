@@ -2,34 +2,58 @@ package handlers
 
 import (
 	"log"
-	"os"
+	"net/http"
+	"time"
 
-	"github.com/gorilla/sessions"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/config"
 )
 
 var (
-	store       *sessions.CookieStore
+	store       *DBStore
 	sessionName string
 )
 
-func InitSession() {
-	sessionSecret := os.Getenv("SESSION_SECRET")
-	if sessionSecret == "" {
-		log.Fatal("SESSION_SECRET is not set in .env file")
-	}
-
-	sessionName = os.Getenv("SESSION_NAME")
-	if sessionName == "" {
-		log.Fatal("SESSION_NAME is not set in .env file")
-	}
-
-	store = sessions.NewCookieStore([]byte(sessionSecret))
+// InitSession настраивает хранилище сессий в базе и cookie сессии.
+func InitSession(db *sqlx.DB, cfg config.Session) {
+	sessionName = cfg.Name
+	store = NewDBStore(db, []byte(cfg.Secret))
+	store.Options.MaxAge = int(cfg.MaxAge / time.Second)
+	store.Options.Secure = cfg.Secure
 }
 
-func GetStore() *sessions.CookieStore {
+func GetStore() *DBStore {
 	return store
 }
 
 func GetSessionName() string {
 	return sessionName
 }
+
+// addFlash сохраняет в сессии одноразовое значение для следующего запроса.
+func addFlash(w http.ResponseWriter, r *http.Request, key, value string) error {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		return err
+	}
+	session.AddFlash(value, key)
+	return session.Save(r, w)
+}
+
+// popFlash возвращает и удаляет значение, сохранённое addFlash.
+func popFlash(w http.ResponseWriter, r *http.Request, key string) string {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		return ""
+	}
+	flashes := session.Flashes(key)
+	if len(flashes) == 0 {
+		return ""
+	}
+	if err := session.Save(r, w); err != nil {
+		log.Println("Can't save session:", err)
+	}
+	value, _ := flashes[0].(string)
+	return value
+}
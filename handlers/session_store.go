@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+const (
+	sessionMaxAge = 30 * 24 * 60 * 60
+	// anonymousSessionMaxAge ограничивает в базе сессии без пользователя: они нужны только на время
+	// входа через SSO или второго шага входа, а истёкшие записи удаляет задача очистки сессий.
+	anonymousSessionMaxAge = 24 * 60 * 60
+	sessionTouchInterval   = time.Minute
+)
+
+// DBStore хранит сессии в таблице sessions. Cookie содержит только подписанный случайный токен,
+// поэтому сессию можно завершить на сервере, а список сессий пользователя — показать на странице аккаунта.
+type DBStore struct {
+	DB      *sqlx.DB
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+}
+
+func NewDBStore(db *sqlx.DB, keyPairs ...[]byte) *DBStore {
+	return &DBStore{
+		DB:     db,
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   sessionMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+}
+
+// Get возвращает сессию из реестра запроса, загружая её из базы при первом обращении.
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New загружает сессию по cookie. Если cookie нет, а сессия истекла или была завершена,
+// возвращается новая пустая сессия.
+func (s *DBStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.Codecs...); err != nil {
+		return session, err
+	}
+
+	row, err := models.GetSessionByToken(s.DB, token)
+	if err != nil {
+		return session, err
+	}
+	if row == nil {
+		return session, nil
+	}
+
+	if err := (securecookie.GobEncoder{}).Deserialize(row.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+
+	// Активность отмечается не чаще раза в минуту, чтобы не писать в базу на каждый запрос.
+	if ip := clientIP(r); ip != row.IP || time.Since(row.LastSeenAt) > sessionTouchInterval {
+		if err := row.TouchSession(s.DB, ip); err != nil {
+			log.Printf("Failed to update activity of session %d: %v", row.ID, err)
+		}
+	}
+
+	return session, nil
+}
+
+// Save сохраняет значения сессии в базе и выставляет cookie с её токеном.
+// MaxAge < 0 завершает сессию. Пустые сессии анонимных посетителей в базу не попадают.
+func (s *DBStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := models.DeleteSessionByToken(s.DB, session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" && len(session.Values) == 0 {
+		return nil
+	}
+
+	data, err := securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = sessionMaxAge
+	}
+	userID := sessionUserID(session)
+	if userID == nil {
+		maxAge = min(maxAge, anonymousSessionMaxAge)
+	}
+	row := &models.Session{
+		UserID:    userID,
+		Data:      data,
+		ExpiresAt: time.Now().Add(time.Duration(maxAge) * time.Second),
+	}
+
+	if session.ID == "" {
+		token, err := models.GenerateSessionToken()
+		if err != nil {
+			return err
+		}
+		row.UserAgent = r.UserAgent()
+		row.IP = clientIP(r)
+		if err := row.CreateSession(s.DB, token); err != nil {
+			return err
+		}
+		session.ID = token
+	} else if err := row.UpdateSession(s.DB, session.ID); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Renew удаляет прежнюю запись сессии, чтобы при следующем сохранении она получила новый токен.
+// Вызывается при входе, чтобы токен, выданный до аутентификации, нельзя было использовать после неё.
+func (s *DBStore) Renew(session *sessions.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	if err := models.DeleteSessionByToken(s.DB, session.ID); err != nil {
+		return err
+	}
+	session.ID = ""
+	return nil
+}
+
+func sessionUserID(session *sessions.Session) *int {
+	if userID, ok := session.Values["userID"].(int); ok {
+		return &userID
+	}
+	return nil
+}
+
+// clientIP возвращает адрес клиента без порта.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
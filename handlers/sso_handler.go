@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"NotesWebApp/models"
+	"NotesWebApp/sso"
+)
+
+const (
+	ssoTimeout = 15 * time.Second
+
+	ssoStateKey    = "ssoState"
+	ssoNonceKey    = "ssoNonce"
+	ssoVerifierKey = "ssoVerifier"
+)
+
+var (
+	errSSOEmailUnverified   = errors.New("your identity provider did not confirm your email address")
+	errSSOAccountUnverified = errors.New("an account with this email already exists but its address is not " +
+		"confirmed; sign in with your password and confirm it first")
+)
+
+// SSOLogin перенаправляет пользователя на страницу входа провайдера OpenID Connect.
+// state, nonce и code_verifier сохраняются в сессии до возврата пользователя.
+func (ah *AuthHandler) SSOLogin(w http.ResponseWriter, r *http.Request) {
+	if ah.SSO == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	req, err := sso.NewAuthRequest()
+	if err != nil {
+		log.Printf("Failed to start SSO login: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		return
+	}
+	session.Values[ssoStateKey] = req.State
+	session.Values[ssoNonceKey] = req.Nonce
+	session.Values[ssoVerifierKey] = req.Verifier
+	if err := session.Save(r, w); err != nil {
+		log.Println("Can't save session:", err)
+		return
+	}
+
+	http.Redirect(w, r, ah.SSO.AuthCodeURL(req), http.StatusFound)
+}
+
+// SSOCallback завершает вход после возврата пользователя от провайдера.
+func (ah *AuthHandler) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	if ah.SSO == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		return
+	}
+
+	// Параметры входа одноразовые: удаляем их до любых проверок.
+	state, _ := session.Values[ssoStateKey].(string)
+	nonce, _ := session.Values[ssoNonceKey].(string)
+	verifier, _ := session.Values[ssoVerifierKey].(string)
+	delete(session.Values, ssoStateKey)
+	delete(session.Values, ssoNonceKey)
+	delete(session.Values, ssoVerifierKey)
+	if err := session.Save(r, w); err != nil {
+		log.Println("Can't save session:", err)
+	}
+
+	query := r.URL.Query()
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		ah.renderLogin(w, r, http.StatusBadRequest, loginPage{Error: "The sign-in request has expired, try again"})
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		log.Printf("SSO provider returned an error: %s: %s", errCode, query.Get("error_description"))
+		ah.renderLogin(w, r, http.StatusUnauthorized, loginPage{Error: "Sign-in with " + ah.SSO.Name + " was cancelled"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ssoTimeout)
+	defer cancel()
+
+	req := sso.AuthRequest{State: state, Nonce: nonce, Verifier: verifier}
+	identity, err := ah.SSO.Exchange(ctx, req, query.Get("code"))
+	if err != nil {
+		log.Printf("SSO login failed: %v", err)
+		ah.renderLogin(w, r, http.StatusUnauthorized, loginPage{Error: "Sign-in with " + ah.SSO.Name + " failed"})
+		return
+	}
+
+	user, err := ah.ssoUser(r, identity)
+	if errors.Is(err, errSSOEmailUnverified) || errors.Is(err, errSSOAccountUnverified) {
+		ah.renderLogin(w, r, http.StatusForbidden, loginPage{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to find user for SSO subject %q: %v", identity.Subject, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if user.IsDisabled() {
+		ah.renderLogin(w, r, http.StatusForbidden, loginPage{Error: errAccountDisabled})
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		ah.beginTwoFactor(w, r, user.ID)
+		return
+	}
+	ah.signIn(w, r, user)
+}
+
+// ssoUser находит пользователя, связанного с учётной записью провайдера. При первом входе учётная запись
+// связывается с пользователем с тем же подтверждённым адресом, а если такого нет — создаётся новый пользователь.
+func (ah *AuthHandler) ssoUser(r *http.Request, identity *sso.Identity) (*models.User, error) {
+	user, err := models.GetUserByIdentity(ah.DB, identity.Issuer, identity.Subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errSSOEmailUnverified
+	}
+
+	user, err = models.GetUserByEmail(ah.DB, identity.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = models.CreateUserWithIdentity(ah.DB, identity.Email, identity.Issuer, identity.Subject)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("User %d registered via SSO", user.ID)
+		ah.auditRegistration(r, user, "via "+ah.SSO.Name)
+		return user, nil
+	case err != nil:
+		return nil, err
+	}
+
+	// Неподтверждённый адрес мог зарегистрировать кто угодно, поэтому такой аккаунт не связываем:
+	// иначе владелец пароля получил бы доступ к заметкам настоящего владельца адреса.
+	if !user.IsVerified() {
+		return nil, errSSOAccountUnverified
+	}
+
+	if err := models.LinkIdentity(ah.DB, user.ID, identity.Issuer, identity.Subject); err != nil {
+		return nil, err
+	}
+	log.Printf("User %d linked an SSO identity", user.ID)
+	return user, nil
+}
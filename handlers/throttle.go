@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+// throttleCheck связывает правило ограничения с видом действия и ключом, по которым считаются попытки.
+type throttleCheck struct {
+	rule models.LoginThrottle
+	kind string
+	key  string
+}
+
+// throttleWait возвращает, сколько ещё нужно подождать по самому строгому из правил checks,
+// или 0, если действие можно выполнить.
+func throttleWait(db *sqlx.DB, checks ...throttleCheck) (time.Duration, error) {
+	now := time.Now()
+
+	var retryAt time.Time
+	for _, check := range checks {
+		events, err := models.CountThrottleEvents(db, check.kind, check.key, now.Add(-check.rule.Window))
+		if err != nil {
+			return 0, err
+		}
+		if at := check.rule.RetryAt(events); at.After(retryAt) {
+			retryAt = at
+		}
+	}
+	return max(retryAt.Sub(now), 0), nil
+}
+
+// recordThrottle учитывает действие по каждому из checks; ошибка только пишется в лог.
+func recordThrottle(db *sqlx.DB, checks ...throttleCheck) {
+	for _, check := range checks {
+		if err := models.RecordThrottleEvent(db, check.kind, check.key); err != nil {
+			log.Printf("Failed to record throttle event %s: %v", check.kind, err)
+		}
+	}
+}
+
+// retryAfter выставляет заголовок Retry-After и возвращает паузу wait в виде "N seconds" или "N minutes".
+func retryAfter(w http.ResponseWriter, wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	if wait > time.Minute {
+		return fmt.Sprintf("%d minutes", int(math.Ceil(wait.Minutes())))
+	}
+	return fmt.Sprintf("%d seconds", seconds)
+}
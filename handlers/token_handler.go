@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+const maxTokenLifetimeDays = 3650
+
+type TokenHandler struct {
+	DB *sqlx.DB
+}
+
+func NewTokenHandler(db *sqlx.DB) *TokenHandler {
+	return &TokenHandler{DB: db}
+}
+
+type tokensPage struct {
+	Tokens   []models.APIToken
+	NewToken string
+	Error    string
+}
+
+func (th *TokenHandler) render(w http.ResponseWriter, r *http.Request, userID int, page tokensPage) {
+	tokens, err := models.GetAPITokensByUser(th.DB, userID)
+	if err != nil {
+		log.Printf("Failed to list tokens for user %d: %v", userID, err)
+		http.Error(w, "Failed to list access tokens", http.StatusInternalServerError)
+		return
+	}
+	page.Tokens = tokens
+
+	tmpl := template.Must(template.New("tokens.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/tokens.html"))
+	err = tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing tokens.html:", err)
+		return
+	}
+}
+
+func (th *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	th.render(w, r, userID, tokensPage{})
+}
+
+func (th *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		th.render(w, r, userID, tokensPage{Error: "Token name is required"})
+		return
+	}
+
+	token := &models.APIToken{
+		UserID: userID,
+		Name:   name,
+	}
+
+	if days := r.FormValue("expires_in_days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 || n > maxTokenLifetimeDays {
+			w.WriteHeader(http.StatusBadRequest)
+			th.render(w, r, userID, tokensPage{Error: "Invalid token lifetime"})
+			return
+		}
+		expiresAt := time.Now().AddDate(0, 0, n)
+		token.ExpiresAt = &expiresAt
+	}
+
+	plain, err := models.GenerateAPIToken()
+	if err != nil {
+		log.Printf("Failed to generate token for user %d: %v", userID, err)
+		http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
+		return
+	}
+
+	if err := token.CreateAPIToken(th.DB, plain); err != nil {
+		log.Printf("Failed to create token for user %d: %v", userID, err)
+		http.Error(w, "Failed to create access token", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d created access token %d", userID, token.ID)
+	th.render(w, r, userID, tokensPage{NewToken: plain})
+}
+
+func (th *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := models.DeleteAPIToken(th.DB, id, userID)
+	if err != nil {
+		log.Printf("Failed to revoke token %d: %v", id, err)
+		http.Error(w, "Failed to revoke access token", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("User %d revoked access token %d", userID, id)
+	http.Redirect(w, r, "/tokens", http.StatusSeeOther)
+}
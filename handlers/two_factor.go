@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/jmoiron/sqlx"
+	"rsc.io/qr"
+
+	"NotesWebApp/models"
+	"NotesWebApp/totp"
+)
+
+const (
+	twoFactorIssuer = "Notes"
+	// twoFactorTimeout — сколько времени после ввода пароля можно ввести код второго фактора.
+	twoFactorTimeout = 5 * time.Minute
+	// maxTwoFactorAttempts — после стольких неверных кодов пароль придётся ввести заново.
+	maxTwoFactorAttempts = 5
+
+	pendingUserKey     = "pendingUserID"
+	pendingSinceKey    = "pendingSince"
+	pendingAttemptsKey = "pendingAttempts"
+	totpSetupKey       = "totpSetupSecret"
+	accountFlashKey    = "account"
+)
+
+type twoFactorLoginPage struct {
+	Error   string
+	Expired bool
+}
+
+type twoFactorSetupPage struct {
+	Secret        string
+	RecoveryCodes []string
+	Error         string
+}
+
+// clearPendingLogin удаляет из сессии состояние незавершённого входа.
+func clearPendingLogin(session *sessions.Session) {
+	delete(session.Values, pendingUserKey)
+	delete(session.Values, pendingSinceKey)
+	delete(session.Values, pendingAttemptsKey)
+}
+
+// pendingLogin возвращает пользователя, который ввёл пароль и ещё не ввёл код второго фактора.
+func pendingLogin(session *sessions.Session) (int, bool) {
+	userID, ok := session.Values[pendingUserKey].(int)
+	if !ok {
+		return 0, false
+	}
+	since, ok := session.Values[pendingSinceKey].(int64)
+	if !ok || time.Since(time.Unix(since, 0)) > twoFactorTimeout {
+		return 0, false
+	}
+	return userID, true
+}
+
+// beginTwoFactor запоминает пользователя, правильно введшего пароль, и отправляет его на ввод кода.
+// userID в сессию не записывается, поэтому до ввода кода пользователь не считается вошедшим.
+func (ah *AuthHandler) beginTwoFactor(w http.ResponseWriter, r *http.Request, userID int) {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+
+		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		return
+	}
+	session.Values[pendingUserKey] = userID
+	session.Values[pendingSinceKey] = time.Now().Unix()
+	session.Values[pendingAttemptsKey] = 0
+	if err := session.Save(r, w); err != nil {
+		log.Println("Can't save session:", err)
+		return
+	}
+	http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+}
+
+func (ah *AuthHandler) renderTwoFactorLogin(
+	w http.ResponseWriter, r *http.Request, status int, page twoFactorLoginPage,
+) {
+	tmpl := template.Must(template.New("login_2fa.html").Funcs(csrfFuncs(w, r)).ParseFiles("templates/login_2fa.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing login_2fa.html:", err)
+		return
+	}
+}
+
+func (ah *AuthHandler) TwoFactorForm(w http.ResponseWriter, r *http.Request) {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		return
+	}
+	if _, ok := pendingLogin(session); !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	ah.renderTwoFactorLogin(w, r, http.StatusOK, twoFactorLoginPage{})
+}
+
+// TwoFactorLogin завершает вход кодом из приложения-аутентификатора или кодом восстановления.
+func (ah *AuthHandler) TwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+
+		http.Error(w, "Failed to get session", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := pendingLogin(session)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	user, err := models.GetUserByID(ah.DB, userID)
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", userID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || !user.TwoFactorEnabled() || user.IsDisabled() {
+		clearPendingLogin(session)
+		if err := session.Save(r, w); err != nil {
+			log.Println("Can't save session:", err)
+		}
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Коды второго фактора подбираются так же, как пароль, поэтому на них действуют те же ограничения.
+	key := throttleKey(user.Email)
+	ip := clientIP(r)
+	wait, err := ah.loginRetryAfter(key, ip)
+	if err != nil {
+		log.Printf("Failed to check login attempts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		clearPendingLogin(session)
+		if err := session.Save(r, w); err != nil {
+			log.Println("Can't save session:", err)
+		}
+		ah.renderLoginThrottled(w, r, wait)
+		return
+	}
+
+	valid, err := checkSecondFactor(ah.DB, user, strings.TrimSpace(r.FormValue("code")))
+	if err != nil {
+		log.Printf("Failed to check second factor for user %d: %v", userID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		ah.recordLoginAttempt(key, &user.ID, ip, false)
+
+		attempts, _ := session.Values[pendingAttemptsKey].(int)
+		attempts++
+		page := twoFactorLoginPage{Error: "Invalid code"}
+		if attempts >= maxTwoFactorAttempts {
+			log.Printf("Too many invalid second factor codes for user %d", userID)
+			clearPendingLogin(session)
+			page = twoFactorLoginPage{Expired: true}
+		} else {
+			session.Values[pendingAttemptsKey] = attempts
+		}
+		if err := session.Save(r, w); err != nil {
+			log.Println("Can't save session:", err)
+			return
+		}
+		ah.renderTwoFactorLogin(w, r, http.StatusUnauthorized, page)
+		return
+	}
+
+	ah.recordLoginAttempt(key, &user.ID, ip, true)
+	ah.signIn(w, r, user)
+}
+
+// checkSecondFactor проверяет код TOTP, а если он не подошёл, — код восстановления.
+// Каждый код принимается только один раз.
+func checkSecondFactor(db *sqlx.DB, user *models.User, code string) (bool, error) {
+	if code == "" || user.TOTPSecret == nil {
+		return false, nil
+	}
+
+	if step, ok := totp.Validate(*user.TOTPSecret, code, time.Now()); ok {
+		return models.UseTOTPStep(db, user.ID, step)
+	}
+
+	used, err := models.UseRecoveryCode(db, user.ID, code)
+	if used {
+		log.Printf("User %d used a recovery code", user.ID)
+	}
+	return used, err
+}
+
+func (ach *AccountHandler) renderTwoFactorSetup(
+	w http.ResponseWriter, r *http.Request, status int, page twoFactorSetupPage,
+) {
+	// На странице секрет и коды восстановления, их не должно быть в кеше браузера.
+	w.Header().Set("Cache-Control", "no-store")
+
+	tmpl := template.Must(template.New("two_factor_setup.html").Funcs(csrfFuncs(w, r)).
+		ParseFiles("templates/two_factor_setup.html"))
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, page)
+	if err != nil {
+		log.Println("Error while executing two_factor_setup.html:", err)
+		return
+	}
+}
+
+// setupSecret возвращает секрет, который пользователь настраивает, создавая новый при первом обращении.
+// До подтверждения кодом секрет хранится только в сессии.
+func setupSecret(w http.ResponseWriter, r *http.Request, create bool) (string, error) {
+	session, err := store.Get(r, sessionName)
+	if err != nil {
+		return "", err
+	}
+	if secret, ok := session.Values[totpSetupKey].(string); ok || !create {
+		return secret, nil
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	session.Values[totpSetupKey] = secret
+	return secret, session.Save(r, w)
+}
+
+// TwoFactorSetupForm показывает QR-код и секрет для приложения-аутентификатора.
+func (ach *AccountHandler) TwoFactorSetupForm(w http.ResponseWriter, r *http.Request) {
+	user, ok := ach.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled() {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+
+	secret, err := setupSecret(w, r, true)
+	if err != nil {
+		log.Printf("Failed to start two-factor setup for user %d: %v", user.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ach.renderTwoFactorSetup(w, r, http.StatusOK, twoFactorSetupPage{Secret: secret})
+}
+
+// TwoFactorQRCode отдаёт QR-код со ссылкой otpauth:// для настраиваемого секрета.
+func (ach *AccountHandler) TwoFactorQRCode(w http.ResponseWriter, r *http.Request) {
+	user, ok := ach.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := setupSecret(w, r, false)
+	if err != nil || secret == "" || user.TwoFactorEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	code, err := qr.Encode(totp.URI(twoFactorIssuer, user.Email, secret), qr.M)
+	if err != nil {
+		log.Printf("Failed to encode QR code: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write(code.PNG()); err != nil {
+		log.Println("Failed to write QR code:", err)
+	}
+}
+
+// EnableTwoFactor включает двухфакторную аутентификацию после проверки кода из приложения
+// и один раз показывает коды восстановления.
+func (ach *AccountHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := ach.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := setupSecret(w, r, false)
+	if err != nil || secret == "" {
+		http.Redirect(w, r, "/account/2fa/setup", http.StatusSeeOther)
+		return
+	}
+
+	step, valid := totp.Validate(secret, r.FormValue("code"), time.Now())
+	if !valid {
+		ach.renderTwoFactorSetup(w, r, http.StatusBadRequest, twoFactorSetupPage{
+			Secret: secret,
+			Error:  "Invalid code. Check that the time on your device is correct and try again",
+		})
+		return
+	}
+
+	codes, err := models.GenerateRecoveryCodes()
+	if err != nil {
+		log.Printf("Failed to generate recovery codes: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = models.EnableTwoFactor(ach.DB, user.ID, secret, step, codes)
+	if errors.Is(err, models.ErrTwoFactorEnabled) {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to enable two-factor authentication for user %d: %v", user.ID, err)
+		http.Error(w, "Internal server error: failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := store.Get(r, sessionName)
+	if err == nil {
+		delete(session.Values, totpSetupKey)
+		err = session.Save(r, w)
+	}
+	if err != nil {
+		log.Println("Can't save session:", err)
+	}
+
+	log.Printf("User %d enabled two-factor authentication", user.ID)
+	ach.renderTwoFactorSetup(w, r, http.StatusOK, twoFactorSetupPage{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию после повторного ввода пароля или,
+// например у пользователей, входящих только через SSO, кода второго фактора. Неверные попытки
+// ограничиваются так же, как попытки входа в аккаунт.
+func (ach *AccountHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := ach.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	checks := []throttleCheck{{accountLoginThrottle, models.ThrottleTwoFactorDisable, strconv.Itoa(user.ID)}}
+	wait, err := throttleWait(ach.DB, checks...)
+	if err != nil {
+		log.Printf("Failed to check two-factor disable attempts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		message := "Too many failed attempts. Try again in " + retryAfter(w, wait)
+		if err := addFlash(w, r, accountFlashKey, message); err != nil {
+			log.Println("Can't save session:", err)
+		}
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+
+	valid := false
+	if password := r.FormValue("password"); password != "" {
+		valid = checkPassword(user, password)
+	}
+	if code := strings.TrimSpace(r.FormValue("code")); !valid && code != "" {
+		valid, err = checkSecondFactor(ach.DB, user, code)
+		if err != nil {
+			log.Printf("Failed to check second factor for user %d: %v", user.ID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if !valid {
+		recordThrottle(ach.DB, checks...)
+		message := "Wrong password or code, two-factor authentication is still on"
+		if err := addFlash(w, r, accountFlashKey, message); err != nil {
+			log.Println("Can't save session:", err)
+		}
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+
+	if err := models.DisableTwoFactor(ach.DB, user.ID); err != nil {
+		log.Printf("Failed to disable two-factor authentication for user %d: %v", user.ID, err)
+		http.Error(w, "Internal server error: failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %d disabled two-factor authentication", user.ID)
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
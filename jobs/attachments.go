@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+	"NotesWebApp/storage"
+)
+
+const attachmentPurgeBatch = 100
+
+// PurgeAttachments периодически удаляет из хранилища содержимое вложений окончательно удалённых заметок.
+// Работает до отмены ctx.
+func PurgeAttachments(ctx context.Context, db *sqlx.DB, store storage.Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeAttachmentsOnce(ctx, db, store)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeAttachmentsOnce(ctx context.Context, db *sqlx.DB, store storage.Storage) {
+	attachments, err := models.GetOrphanedAttachments(db, attachmentPurgeBatch)
+	if err != nil {
+		log.Println("Failed to list orphaned attachments:", err)
+		return
+	}
+
+	purged := 0
+	for i := range attachments {
+		// Запись удаляется только после содержимого, чтобы при ошибке повторить попытку позже.
+		if err := store.Delete(ctx, attachments[i].StorageKey); err != nil {
+			log.Printf("Failed to delete attachment %d from storage: %v", attachments[i].ID, err)
+			continue
+		}
+		if err := attachments[i].DeleteAttachment(db); err != nil {
+			log.Printf("Failed to delete attachment %d: %v", attachments[i].ID, err)
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		log.Printf("Purged %d attachments of deleted notes", purged)
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+// PurgeLoginAttempts периодически удаляет записи о попытках входа старше retention.
+// Работает до отмены ctx.
+func PurgeLoginAttempts(ctx context.Context, db *sqlx.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeLoginAttemptsOnce(db, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeLoginAttemptsOnce(db *sqlx.DB, retention time.Duration) {
+	purged, err := models.PurgeLoginAttempts(db, time.Now().Add(-retention))
+	if err != nil {
+		log.Println("Failed to purge login attempts:", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d old login attempts", purged)
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+// PurgeSessions периодически удаляет из базы истёкшие сессии.
+// Работает до отмены ctx.
+func PurgeSessions(ctx context.Context, db *sqlx.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeSessionsOnce(db)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeSessionsOnce(db *sqlx.DB) {
+	purged, err := models.PurgeExpiredSessions(db, time.Now())
+	if err != nil {
+		log.Println("Failed to purge expired sessions:", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d expired sessions", purged)
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+// PurgeThrottleEvents периодически удаляет ограничиваемые действия старше retention.
+// Работает до отмены ctx.
+func PurgeThrottleEvents(ctx context.Context, db *sqlx.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeThrottleEventsOnce(db, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeThrottleEventsOnce(db *sqlx.DB, retention time.Duration) {
+	purged, err := models.PurgeThrottleEvents(db, time.Now().Add(-retention))
+	if err != nil {
+		log.Println("Failed to purge throttle events:", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d old throttle events", purged)
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"NotesWebApp/models"
+)
+
+// PurgeTrash периодически удаляет заметки, пролежавшие в корзине дольше retention.
+// Работает до отмены ctx.
+func PurgeTrash(ctx context.Context, db *sqlx.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeTrashOnce(db, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeTrashOnce(db *sqlx.DB, retention time.Duration) {
+	purged, err := models.PurgeTrash(db, time.Now().Add(-retention))
+	if err != nil {
+		log.Println("Failed to purge trash:", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d notes from trash", purged)
+	}
+}
@@ -0,0 +1,74 @@
+// Package mail отправляет письма пользователям: ссылки для сброса пароля и подобные уведомления.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+var ErrInvalidMessage = errors.New("mail: invalid message")
+
+// Message — простое текстовое письмо одному получателю.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письма. Реализация выбирается при запуске: SMTP или вывод в лог для разработки.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// recipient проверяет письмо и возвращает адрес получателя без отображаемого имени.
+func (m Message) recipient() (string, error) {
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return "", fmt.Errorf("%w: subject contains a line break", ErrInvalidMessage)
+	}
+	addr, err := netmail.ParseAddress(m.To)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	return addr.Address, nil
+}
+
+// build собирает письмо в формате RFC 5322 с телом в quoted-printable.
+func (m Message) build(from, to string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(m.Body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// LogSender не отправляет письма, а выводит их в лог. Подходит только для локальной разработки.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, msg Message) error {
+	to, err := msg.recipient()
+	if err != nil {
+		return err
+	}
+	log.Printf("Mail to %s: %s\n%s", to, msg.Subject, msg.Body)
+	return nil
+}
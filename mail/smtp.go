@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSMTPPort = 587
+	// smtpsPort — порт SMTP с неявным TLS, на нём шифрование включается сразу при подключении.
+	smtpsPort = 465
+)
+
+// SMTPConfig описывает почтовый сервер для исходящих писем.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP отправляет письма через SMTP-сервер. Если сервер поддерживает STARTTLS, соединение шифруется;
+// учётные данные net/smtp передаёт только по зашифрованному соединению или на localhost.
+type SMTP struct {
+	cfg    SMTPConfig
+	from   *netmail.Address
+	addr   string
+	dialer *net.Dialer
+	now    func() time.Time
+}
+
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("mail: SMTP host is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = defaultSMTPPort
+	}
+
+	from, err := netmail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid sender address: %w", err)
+	}
+
+	return &SMTP{
+		cfg:    cfg,
+		from:   from,
+		addr:   net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		dialer: &net.Dialer{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	to, err := msg.recipient()
+	if err != nil {
+		return err
+	}
+	data, err := msg.build(s.from.String(), to, s.now())
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("mail: connect to %s: %w", s.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}
+	if s.cfg.Port == smtpsPort {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mail: %w", err)
+	}
+	defer client.Close()
+
+	if err := s.deliver(client, tlsConfig, to, data); err != nil {
+		return fmt.Errorf("mail: send to %s: %w", to, err)
+	}
+	return nil
+}
+
+func (s *SMTP) deliver(client *smtp.Client, tlsConfig *tls.Config, to string, data []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok && s.cfg.Port != smtpsPort {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,191 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP — минимальный SMTP-сервер на localhost, который принимает письма и сохраняет их в памяти.
+type fakeSMTP struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	auth     string
+	from     string
+	rcpt     []string
+	messages []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	fake := &fakeSMTP{listener: listener}
+	go fake.serve()
+	return fake
+}
+
+func (f *fakeSMTP) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	reply := func(line string) { _ = tp.PrintfLine("%s", line) }
+	reply("220 localhost fake SMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			f.mu.Lock()
+			f.auth = string(decoded)
+			f.mu.Unlock()
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			f.mu.Lock()
+			f.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			f.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			f.mu.Lock()
+			f.rcpt = append(f.rcpt, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			f.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, string(data))
+			f.mu.Unlock()
+			reply("250 OK: queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	fake := newFakeSMTP(t)
+
+	sender, err := NewSMTP(SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     fake.port(),
+		Username: "mailer",
+		Password: "secret",
+		From:     "Notes <noreply@example.com>",
+	})
+	require.NoError(t, err)
+	sender.now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = sender.Send(ctx, Message{
+		To:      "alice@example.com",
+		Subject: "Сброс пароля",
+		Body:    "Follow the link:\nhttps://notes.example.com/password/reset/abc\n.\n",
+	})
+	require.NoError(t, err)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	assert.Equal(t, "\x00mailer\x00secret", fake.auth)
+	assert.Equal(t, "noreply@example.com", fake.from)
+	assert.Equal(t, []string{"alice@example.com"}, fake.rcpt)
+	require.Len(t, fake.messages, 1)
+
+	header, body, found := strings.Cut(fake.messages[0], "\n\n")
+	require.True(t, found)
+	assert.Contains(t, header, `From: "Notes" <noreply@example.com>`)
+	assert.Contains(t, header, "To: alice@example.com")
+	assert.Contains(t, header, "Subject: =?utf-8?q?")
+	assert.Contains(t, header, "Date: Sun, 18 Oct 2026 12:00:00 +0000")
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	require.NoError(t, err)
+	assert.Equal(t, "Follow the link:\nhttps://notes.example.com/password/reset/abc\n.\n",
+		strings.ReplaceAll(string(decoded), "\r\n", "\n"))
+}
+
+func TestSMTP_Send_InvalidMessage(t *testing.T) {
+	sender, err := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"})
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi\r\nBcc: eve@example.com"})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	err = sender.Send(context.Background(), Message{To: "not an address", Subject: "Hi"})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestNewSMTP_Validation(t *testing.T) {
+	_, err := NewSMTP(SMTPConfig{From: "noreply@example.com"})
+	assert.Error(t, err)
+
+	_, err = NewSMTP(SMTPConfig{Host: "smtp.example.com", From: "not an address"})
+	assert.Error(t, err)
+
+	sender, err := NewSMTP(SMTPConfig{Host: "smtp.example.com", From: "noreply@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:587", sender.addr)
+}
+
+func TestMessage_Build(t *testing.T) {
+	data, err := Message{Subject: "Hello", Body: "line one\nline two"}.
+		build("noreply@example.com", "bob@example.com", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+
+	text := string(data)
+	assert.Contains(t, text, "Subject: Hello\r\n")
+	assert.Contains(t, text, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	assert.True(t, strings.HasSuffix(text, "line one\r\nline two"))
+}
+
+func TestLogSender_Send(t *testing.T) {
+	assert.NoError(t, LogSender{}.Send(context.Background(), Message{To: "bob@example.com", Subject: "Hi"}))
+	assert.ErrorIs(t, LogSender{}.Send(context.Background(), Message{To: "", Subject: "Hi"}), ErrInvalidMessage)
+}
@@ -1,68 +1,243 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
+	"NotesWebApp/config"
 	"NotesWebApp/database"
 	"NotesWebApp/handlers"
+	"NotesWebApp/jobs"
+	"NotesWebApp/mail"
+	"NotesWebApp/sso"
+	"NotesWebApp/storage"
+	"NotesWebApp/validation"
 
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
+)
+
+const (
+	loginAttemptRetention = 30 * 24 * time.Hour
+	// throttleEventRetention с запасом больше самого длинного окна правил ограничения.
+	throttleEventRetention = 7 * 24 * time.Hour
+	ssoDiscoveryTimeout    = 30 * time.Second
 )
 
 func runServer(db *sqlx.DB, server *http.Server) error {
 	defer db.Close()
 
-	log.Println("Server is running on port 8080...")
+	log.Printf("Server is listening on %s...", server.Addr)
 	return server.ListenAndServe()
 }
 
-func main() {
-	err := godotenv.Load(".env") // Загружаем переменные окружения
+// attachmentStorage создаёт хранилище вложений: каталог на диске или S3-совместимое хранилище.
+func attachmentStorage(cfg config.Attachments) storage.Storage {
+	if cfg.Storage == "s3" {
+		store, err := storage.NewS3(cfg.S3, &http.Client{Timeout: time.Minute})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return store
+	}
+
+	store, err := storage.NewLocal(cfg.Dir)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal("Failed to prepare attachment directory: ", err)
 	}
+	return store
+}
 
-	handlers.InitSession() // Инициализация сессии
+// mailSender отправляет письма через SMTP или выводит их в лог, если SMTP не настроен.
+func mailSender(cfg mail.SMTPConfig) mail.Sender {
+	if cfg.Host == "" {
+		log.Println("SMTP_HOST is not set, emails will be written to the log instead of being sent")
+		return mail.LogSender{}
+	}
 
-	db, err := database.InitDB() // инициализация базы
+	sender, err := mail.NewSMTP(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return sender
+}
+
+// ssoProvider настраивает вход через OpenID Connect, если задан OIDC_ISSUER.
+func ssoProvider(cfg sso.Config) *sso.Provider {
+	if cfg.Issuer == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ssoDiscoveryTimeout)
+	defer cancel()
+
+	provider, err := sso.New(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return provider
+}
+
+func main() {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv) // флаги, окружение и файл настроек
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("Invalid configuration:\n", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if cfg.File != "" {
+		log.Printf("Loaded configuration file %s", cfg.File)
+	}
+
+	db, err := database.InitDB(cfg.Database.DSN()) // инициализация базы
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handlers.InitSession(db, cfg.Session) // Инициализация сессий, хранящихся в базе
+
+	store := attachmentStorage(cfg.Attachments)
+
+	// фоновая очистка корзины, вложений удалённых заметок, истёкших сессий, старых попыток входа и счётчиков ограничений
+	go jobs.PurgeTrash(context.Background(), db, cfg.TrashRetention, time.Hour)
+	go jobs.PurgeAttachments(context.Background(), db, store, time.Hour)
+	go jobs.PurgeSessions(context.Background(), db, time.Hour)
+	go jobs.PurgeLoginAttempts(context.Background(), db, loginAttemptRetention, time.Hour)
+	go jobs.PurgeThrottleEvents(context.Background(), db, throttleEventRetention, time.Hour)
 
 	router := mux.NewRouter() // инициализация роутера
 
 	// инициализация обработчиков
-	noteHandler := handlers.NewNoteHandler(db)
-	authHandler := handlers.NewAuthHandler(db)
+	noteHandler := handlers.NewNoteHandler(db, store, cfg.AppURL)
+	authHandler := handlers.NewAuthHandler(db, mailSender(cfg.SMTP), cfg.AppURL,
+		handlers.VerificationPolicy(cfg.EmailVerification), ssoProvider(cfg.OIDC),
+		validation.PasswordPolicy{MinLength: cfg.PasswordMinLength}, cfg.AdminEmail)
+	noteAPIHandler := handlers.NewNoteAPIHandler(db)
+	tokenHandler := handlers.NewTokenHandler(db)
+	publicLinkHandler := handlers.NewPublicLinkHandler(db)
+	notebookHandler := handlers.NewNotebookHandler(db)
+	accountHandler := handlers.NewAccountHandler(db)
+	adminHandler := handlers.NewAdminHandler(db, authHandler)
+	authMiddleware := handlers.NewAuthMiddleware(db)
+
+	router.Use(authMiddleware.Authenticate) // сессия или Bearer-токен
+	router.Use(handlers.CSRFProtect)        // токен CSRF для изменяющих запросов из браузера
 
 	// маршруты заметок
 	router.HandleFunc("/notes", noteHandler.GetNotes).Methods("GET")
-	router.HandleFunc("/notes/create", noteHandler.CreateNoteForm).Methods("GET")
-	router.HandleFunc("/notes/create", noteHandler.CreateNote).Methods("POST")
+	router.HandleFunc("/notes/search", noteHandler.SearchNotes).Methods("GET")
+	router.HandleFunc("/notes/create", authHandler.RequireVerifiedEmail(noteHandler.CreateNoteForm)).Methods("GET")
+	router.HandleFunc("/notes/create", authHandler.RequireVerifiedEmail(noteHandler.CreateNote)).Methods("POST")
+	router.HandleFunc("/notes/view/{id}", noteHandler.ViewNote).Methods("GET")
 	router.HandleFunc("/notes/edit/{id}", noteHandler.EditNoteForm).Methods("GET")
 	router.HandleFunc("/notes/edit/{id}", noteHandler.EditNote).Methods("POST")
 	router.HandleFunc("/notes/delete/{id}", noteHandler.DeleteNote).Methods("POST")
+	router.HandleFunc("/notes/pin/{id}", noteHandler.PinNote).Methods("POST")
+	router.HandleFunc("/notes/unpin/{id}", noteHandler.UnpinNote).Methods("POST")
+	router.HandleFunc("/notes/archive", noteHandler.Archive).Methods("GET")
+	router.HandleFunc("/notes/archive/{id}", noteHandler.ArchiveNote).Methods("POST")
+	router.HandleFunc("/notes/unarchive/{id}", noteHandler.UnarchiveNote).Methods("POST")
+	router.HandleFunc("/notes/trash", noteHandler.Trash).Methods("GET")
+	router.HandleFunc("/notes/trash/empty", noteHandler.EmptyTrash).Methods("POST")
+	router.HandleFunc("/notes/trash/restore/{id}", noteHandler.RestoreNote).Methods("POST")
+	router.HandleFunc("/notes/trash/delete/{id}", noteHandler.PurgeNote).Methods("POST")
+	router.HandleFunc("/notes/share/{id}", noteHandler.ShareNote).Methods("POST")
+	router.HandleFunc("/notes/share/{id}/remove/{user}", noteHandler.UnshareNote).Methods("POST")
+	router.HandleFunc("/notes/links/{id}", noteHandler.CreatePublicLink).Methods("POST")
+	router.HandleFunc("/notes/links/{id}/revoke/{link}", noteHandler.RevokePublicLink).Methods("POST")
+	router.HandleFunc("/notes/attachments/{id}/download/{attachment}", noteHandler.DownloadAttachment).Methods("GET")
+	router.HandleFunc("/notes/attachments/{id}/delete/{attachment}", noteHandler.DeleteAttachment).Methods("POST")
+	router.HandleFunc("/notes/history/{id}", noteHandler.NoteHistory).Methods("GET")
+	router.HandleFunc("/notes/history/{id}/restore/{revision}", noteHandler.RestoreRevision).Methods("POST")
+
+	// маршруты блокнотов
+	router.HandleFunc("/notebooks", notebookHandler.ListNotebooks).Methods("GET")
+	router.HandleFunc("/notebooks", notebookHandler.CreateNotebook).Methods("POST")
+	router.HandleFunc("/notebooks/rename/{id}", notebookHandler.RenameNotebook).Methods("POST")
+	router.HandleFunc("/notebooks/delete/{id}", notebookHandler.DeleteNotebook).Methods("POST")
+
+	// публичные ссылки на заметки, доступные без входа
+	router.HandleFunc("/p/{token}", publicLinkHandler.ShowNote).Methods("GET")
+	router.HandleFunc("/p/{token}", publicLinkHandler.UnlockNote).Methods("POST")
+
+	// маршруты персональных токенов доступа; ими, как и сессиями и вторым фактором ниже,
+	// можно управлять только из браузера, но не по Bearer-токену
+	router.HandleFunc("/tokens", handlers.RequireSession(tokenHandler.ListTokens)).Methods("GET")
+	router.HandleFunc("/tokens", handlers.RequireSession(tokenHandler.CreateToken)).Methods("POST")
+	router.HandleFunc("/tokens/revoke/{id}", handlers.RequireSession(tokenHandler.RevokeToken)).Methods("POST")
+
+	// страница аккаунта и управление сессиями на устройствах
+	router.HandleFunc("/account", accountHandler.Account).Methods("GET")
+	router.HandleFunc("/account/sessions/revoke/{id}", handlers.RequireSession(accountHandler.RevokeSession)).
+		Methods("POST")
+	router.HandleFunc("/account/sessions/revoke-all", handlers.RequireSession(accountHandler.RevokeAllSessions)).
+		Methods("POST")
+	router.HandleFunc("/account/2fa/setup", handlers.RequireSession(accountHandler.TwoFactorSetupForm)).Methods("GET")
+	router.HandleFunc("/account/2fa/setup", handlers.RequireSession(accountHandler.EnableTwoFactor)).Methods("POST")
+	router.HandleFunc("/account/2fa/qr.png", handlers.RequireSession(accountHandler.TwoFactorQRCode)).Methods("GET")
+	router.HandleFunc("/account/2fa/disable", handlers.RequireSession(accountHandler.DisableTwoFactor)).
+		Methods("POST")
+	router.HandleFunc("/account/activity", accountHandler.Activity).Methods("GET")
+	router.HandleFunc("/account/activity/export", accountHandler.ExportActivity).Methods("GET")
+
+	// консоль администратора
+	router.HandleFunc("/admin", adminHandler.RequireAdmin(adminHandler.Dashboard)).Methods("GET")
+	router.HandleFunc("/admin/users/{id}/disable", adminHandler.RequireAdmin(adminHandler.DisableUser)).Methods("POST")
+	router.HandleFunc("/admin/users/{id}/enable", adminHandler.RequireAdmin(adminHandler.EnableUser)).Methods("POST")
+	router.HandleFunc("/admin/users/{id}/reset-password", adminHandler.RequireAdmin(adminHandler.ResetUserPassword)).
+		Methods("POST")
+	router.HandleFunc("/admin/users/{id}/delete", adminHandler.RequireAdmin(adminHandler.DeleteUser)).Methods("POST")
+	router.HandleFunc("/admin/audit", adminHandler.RequireAdmin(adminHandler.AuditLog)).Methods("GET")
+	router.HandleFunc("/admin/audit/export", adminHandler.RequireAdmin(adminHandler.ExportAuditLog)).Methods("GET")
+
+	// маршруты JSON API
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/notes", noteAPIHandler.ListNotes).Methods("GET")
+	api.HandleFunc("/notes", authHandler.RequireVerifiedEmail(noteAPIHandler.CreateNote)).Methods("POST")
+	api.HandleFunc("/notes/search", noteAPIHandler.SearchNotes).Methods("GET")
+	api.HandleFunc("/notes/{id:[0-9]+}", noteAPIHandler.GetNote).Methods("GET")
+	api.HandleFunc("/notes/{id:[0-9]+}", noteAPIHandler.UpdateNote).Methods("PUT")
+	api.HandleFunc("/notes/{id:[0-9]+}", noteAPIHandler.DeleteNote).Methods("DELETE")
 
 	// маршруты аутентификации
 	router.HandleFunc("/", authHandler.Index).Methods("GET")
 	router.HandleFunc("/login", authHandler.LoginForm).Methods("GET")
 	router.HandleFunc("/login", authHandler.Login).Methods("POST")
+	router.HandleFunc("/login/2fa", authHandler.TwoFactorForm).Methods("GET")
+	router.HandleFunc("/login/2fa", authHandler.TwoFactorLogin).Methods("POST")
+	router.HandleFunc("/login/oidc", authHandler.SSOLogin).Methods("GET")
+	router.HandleFunc("/login/oidc/callback", authHandler.SSOCallback).Methods("GET")
 	router.HandleFunc("/register", authHandler.RegisterForm).Methods("GET")
 	router.HandleFunc("/register", authHandler.Register).Methods("POST")
 	router.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	router.HandleFunc("/verify-email", authHandler.VerifyEmailForm).Methods("GET")
+	router.HandleFunc("/verify-email", authHandler.ResendVerification).Methods("POST")
+	router.HandleFunc("/verify-email/{token}", authHandler.VerifyEmail).Methods("GET")
+	router.HandleFunc("/password/forgot", authHandler.ForgotPasswordForm).Methods("GET")
+	router.HandleFunc("/password/forgot", authHandler.ForgotPassword).Methods("POST")
+	router.HandleFunc("/password/reset/{token}", authHandler.ResetPasswordForm).Methods("GET")
+	router.HandleFunc("/password/reset/{token}", authHandler.ResetPassword).Methods("POST")
 
 	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
 	server := &http.Server{
-		Addr:         ":8080",
+		Addr:         cfg.Server.Addr,
 		Handler:      router,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  30 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	if err := runServer(db, server); err != nil {
@@ -0,0 +1,51 @@
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+)
+
+const highlightStyle = "github"
+
+var (
+	converter = goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM, // таблицы, списки задач, зачёркивание, автоссылки
+			highlighting.NewHighlighting(
+				highlighting.WithStyle(highlightStyle),
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+	)
+
+	policy = newPolicy()
+)
+
+// newPolicy разрешает всё, что допускает UGC-политика, а также классы подсветки
+// синтаксиса и отключённые чекбоксы списков задач.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)).
+		OnElements("pre", "code", "span")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$|^checked$|^disabled$`)).
+		OnElements("input")
+	return p
+}
+
+// Render преобразует Markdown (CommonMark + GFM) в безопасный HTML.
+// Сырой HTML в исходном тексте не выводится, а результат дополнительно очищается.
+func Render(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes())), nil //nolint:gosec // HTML очищен политикой
+}
@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "paragraphs and lists",
+			source:   "first line\n\n- one\n- two",
+			contains: []string{"<p>first line</p>", "<li>one</li>", "<li>two</li>"},
+		},
+		{
+			name:     "table",
+			source:   "| a | b |\n|---|---|\n| 1 | 2 |",
+			contains: []string{"<table>", "<th>a</th>", "<td>2</td>"},
+		},
+		{
+			name:     "task list",
+			source:   "- [x] done\n- [ ] todo",
+			contains: []string{`<input checked="" disabled="" type="checkbox"`, `<input disabled="" type="checkbox"`},
+		},
+		{
+			name:     "fenced code is highlighted",
+			source:   "```go\nfunc main() {}\n```",
+			contains: []string{`<pre class="chroma">`, `<span class="kd">func</span>`},
+		},
+		{
+			name:     "raw html is dropped",
+			source:   "<script>alert(1)</script>\n\n<b onclick=\"x()\">bold</b>",
+			excludes: []string{"<script", "onclick", "alert(1)"},
+		},
+		{
+			name:     "javascript links are removed",
+			source:   "[click](javascript:alert(1))",
+			excludes: []string{"javascript:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Render(tt.source)
+			assert.NoError(t, err)
+			for _, s := range tt.contains {
+				assert.Contains(t, string(out), s)
+			}
+			for _, s := range tt.excludes {
+				assert.False(t, strings.Contains(string(out), s), "unexpected %q in %s", s, out)
+			}
+		})
+	}
+}
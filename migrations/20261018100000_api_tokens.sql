@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+                       id SERIAL PRIMARY KEY,
+                       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       name VARCHAR(255) NOT NULL,
+                       token_prefix VARCHAR(16) NOT NULL,
+                       token_hash CHAR(64) UNIQUE NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       last_used_at TIMESTAMP,
+                       expires_at TIMESTAMP
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE notes ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+) STORED;
+
+CREATE INDEX notes_search_vector_idx ON notes USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX notes_search_vector_idx;
+ALTER TABLE notes DROP COLUMN search_vector;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE note_revisions (
+                       id SERIAL PRIMARY KEY,
+                       note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+                       author_id INT REFERENCES users(id) ON DELETE SET NULL,
+                       title VARCHAR(255) NOT NULL,
+                       content TEXT NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX note_revisions_note_id_idx ON note_revisions (note_id, created_at);
+
+-- Текущее состояние существующих заметок становится их первой ревизией.
+INSERT INTO note_revisions (note_id, author_id, title, content, created_at)
+SELECT id, user_id, title, content, updated_at FROM notes;
+
+-- +goose Down
+DROP TABLE note_revisions;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE notes ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX notes_user_id_deleted_at_idx ON notes (user_id, deleted_at);
+
+-- +goose Down
+DROP INDEX notes_user_id_deleted_at_idx;
+ALTER TABLE notes DROP COLUMN deleted_at;
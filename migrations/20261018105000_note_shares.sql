@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE note_shares (
+                       note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+                       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       permission VARCHAR(10) NOT NULL CHECK (permission IN ('viewer', 'editor')),
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX note_shares_user_id_idx ON note_shares (user_id);
+
+-- +goose Down
+DROP TABLE note_shares;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE public_links (
+                       id SERIAL PRIMARY KEY,
+                       note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+                       token_prefix VARCHAR(16) NOT NULL,
+                       token_hash CHAR(64) UNIQUE NOT NULL,
+                       password_hash VARCHAR(255),
+                       expires_at TIMESTAMP,
+                       view_count INT NOT NULL DEFAULT 0,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX public_links_note_id_idx ON public_links (note_id);
+
+-- +goose Down
+DROP TABLE public_links;
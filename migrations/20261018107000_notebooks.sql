@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE notebooks (
+                       id SERIAL PRIMARY KEY,
+                       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       name VARCHAR(100) NOT NULL,
+                       is_default BOOLEAN NOT NULL DEFAULT FALSE,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       UNIQUE (user_id, name)
+);
+
+-- У каждого пользователя не больше одного блокнота по умолчанию.
+CREATE UNIQUE INDEX notebooks_default_idx ON notebooks (user_id) WHERE is_default;
+
+INSERT INTO notebooks (user_id, name, is_default)
+SELECT DISTINCT user_id, 'Notes', TRUE FROM notes WHERE user_id IS NOT NULL;
+
+-- Блокнот нельзя удалить, пока в нём есть заметки: приложение сначала переносит их в блокнот по умолчанию.
+ALTER TABLE notes ADD COLUMN notebook_id INT REFERENCES notebooks(id) ON DELETE RESTRICT;
+
+UPDATE notes SET notebook_id = nb.id FROM notebooks nb WHERE nb.user_id = notes.user_id AND nb.is_default;
+
+-- Заметки без владельца не видны ни в одном запросе приложения, а блокнота для них нет.
+DELETE FROM notes WHERE notebook_id IS NULL;
+
+-- Note.NotebookID — не указатель, поэтому NULL в столбце не допускается.
+ALTER TABLE notes ALTER COLUMN notebook_id SET NOT NULL;
+
+CREATE INDEX notes_notebook_id_idx ON notes (notebook_id);
+
+-- +goose Down
+ALTER TABLE notes ALTER COLUMN notebook_id DROP NOT NULL;
+ALTER TABLE notes DROP COLUMN notebook_id;
+DROP TABLE notebooks;
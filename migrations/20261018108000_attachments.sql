@@ -0,0 +1,17 @@
+-- +goose Up
+-- note_id обнуляется при окончательном удалении заметки, чтобы фоновая задача
+-- могла удалить содержимое вложения из хранилища, а затем и саму запись.
+CREATE TABLE attachments (
+                         id SERIAL PRIMARY KEY,
+                         note_id INT REFERENCES notes(id) ON DELETE SET NULL,
+                         filename VARCHAR(255) NOT NULL,
+                         content_type VARCHAR(255) NOT NULL,
+                         size_bytes BIGINT NOT NULL,
+                         storage_key VARCHAR(255) NOT NULL UNIQUE,
+                         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX attachments_note_id_idx ON attachments (note_id);
+
+-- +goose Down
+DROP TABLE attachments;
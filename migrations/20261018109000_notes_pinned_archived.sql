@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE notes ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE notes DROP COLUMN archived;
+ALTER TABLE notes DROP COLUMN pinned;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Индексы для постраничного вывода списка заметок по дате изменения и создания.
+CREATE INDEX notes_user_id_updated_at_idx ON notes (user_id, updated_at);
+CREATE INDEX notes_user_id_created_at_idx ON notes (user_id, created_at);
+
+-- +goose Down
+DROP INDEX notes_user_id_created_at_idx;
+DROP INDEX notes_user_id_updated_at_idx;
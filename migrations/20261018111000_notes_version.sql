@@ -0,0 +1,6 @@
+-- +goose Up
+-- Счётчик версий для оптимистической блокировки: каждое изменение содержимого увеличивает его на 1.
+ALTER TABLE notes ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE notes DROP COLUMN version;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE sessions (
+                       id SERIAL PRIMARY KEY,
+                       token_hash CHAR(64) UNIQUE NOT NULL,
+                       user_id INT REFERENCES users(id) ON DELETE CASCADE,
+                       data BYTEA NOT NULL,
+                       user_agent TEXT NOT NULL DEFAULT '',
+                       ip VARCHAR(45) NOT NULL DEFAULT '',
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+
+-- +goose Down
+DROP TABLE sessions;
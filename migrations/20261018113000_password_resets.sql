@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE password_resets (
+                       id SERIAL PRIMARY KEY,
+                       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       token_hash CHAR(64) UNIQUE NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       expires_at TIMESTAMP NOT NULL,
+                       used_at TIMESTAMP
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
+
+-- +goose Down
+DROP TABLE password_resets;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMP;
+
+-- пользователи, зарегистрированные до появления проверки, считаются подтверждёнными
+UPDATE users SET verified_at = CURRENT_TIMESTAMP;
+
+CREATE TABLE email_verifications (
+                       id SERIAL PRIMARY KEY,
+                       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       token_hash CHAR(64) UNIQUE NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
+
+-- +goose Down
+DROP TABLE email_verifications;
+ALTER TABLE users DROP COLUMN verified_at;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- учётные записи внешних провайдеров входа (OpenID Connect), связанные с пользователями
+CREATE TABLE user_identities (
+                       id SERIAL PRIMARY KEY,
+                       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       issuer VARCHAR(255) NOT NULL,
+                       subject VARCHAR(255) NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       UNIQUE (issuer, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+-- +goose Down
+DROP TABLE user_identities;
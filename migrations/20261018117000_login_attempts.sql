@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE login_attempts (
+                       id SERIAL PRIMARY KEY,
+                       email VARCHAR(255) NOT NULL,
+                       user_id INT REFERENCES users(id) ON DELETE SET NULL,
+                       ip VARCHAR(45) NOT NULL,
+                       succeeded BOOLEAN NOT NULL,
+                       created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_attempts_email_idx ON login_attempts (email, created_at);
+CREATE INDEX login_attempts_ip_idx ON login_attempts (ip, created_at);
+
+-- +goose Down
+DROP TABLE login_attempts;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- actor_email копирует адрес на момент события, чтобы запись оставалась понятной после удаления пользователя.
+CREATE TABLE audit_events (
+                       id SERIAL PRIMARY KEY,
+                       actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+                       actor_email VARCHAR(255) NOT NULL DEFAULT '',
+                       action VARCHAR(64) NOT NULL,
+                       target_type VARCHAR(32) NOT NULL DEFAULT '',
+                       target_id INT,
+                       details TEXT NOT NULL DEFAULT '',
+                       ip VARCHAR(45) NOT NULL DEFAULT '',
+                       created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
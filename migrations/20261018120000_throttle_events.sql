@@ -0,0 +1,15 @@
+-- +goose Up
+-- throttle_events хранит действия, число которых ограничивается (неверные пароли публичных ссылок и т. п.),
+-- по виду действия и ключу: ссылке, пользователю, адресу или IP.
+CREATE TABLE throttle_events (
+                       id SERIAL PRIMARY KEY,
+                       kind VARCHAR(32) NOT NULL,
+                       key VARCHAR(255) NOT NULL,
+                       created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX throttle_events_key_idx ON throttle_events (kind, key, created_at);
+CREATE INDEX throttle_events_created_at_idx ON throttle_events (created_at);
+
+-- +goose Down
+DROP TABLE throttle_events;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Блокнот по умолчанию теперь создаётся при регистрации; пользователям без заметок и блокнотов
+-- он раньше создавался только при первом открытии списка.
+INSERT INTO notebooks (user_id, name, is_default)
+SELECT u.id, CASE WHEN EXISTS (SELECT 1 FROM notebooks nb WHERE nb.user_id = u.id AND nb.name = 'Notes')
+                  THEN 'Notes (default)' ELSE 'Notes' END, TRUE
+FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM notebooks nb WHERE nb.user_id = u.id AND nb.is_default);
+
+-- +goose Down
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Адреса ищутся без учёта регистра, поэтому и уникальными они должны быть без его учёта.
+-- Если в базе уже есть адреса, отличающиеся только регистром, миграция не применится:
+-- такие аккаунты нужно сначала объединить или переименовать вручную.
+CREATE UNIQUE INDEX users_email_lower_key ON users (LOWER(email));
+
+-- +goose Down
+DROP INDEX users_email_lower_key;
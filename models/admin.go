@@ -0,0 +1,206 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLastAdmin — действие оставило бы приложение без действующего администратора.
+var ErrLastAdmin = errors.New("this is the last active administrator; promote another one first")
+
+// UserSummary — строка списка пользователей в консоли администратора.
+type UserSummary struct {
+	ID          int        `db:"id"`
+	Email       string     `db:"email"`
+	Role        string     `db:"role"`
+	VerifiedAt  *time.Time `db:"verified_at"`
+	DisabledAt  *time.Time `db:"disabled_at"`
+	LastLoginAt *time.Time `db:"last_login_at"`
+	// NoteCount — количество заметок пользователя, не считая корзины.
+	NoteCount int `db:"note_count"`
+}
+
+// SystemStats — сводка по всему приложению для консоли администратора.
+type SystemStats struct {
+	Users           int   `db:"users"`
+	Admins          int   `db:"admins"`
+	DisabledUsers   int   `db:"disabled_users"`
+	Notes           int   `db:"notes"`
+	TrashedNotes    int   `db:"trashed_notes"`
+	Attachments     int   `db:"attachments"`
+	AttachmentBytes int64 `db:"attachment_bytes"`
+	ActiveSessions  int   `db:"active_sessions"`
+	// FailedLogins — неудачные попытки входа с момента, переданного в GetSystemStats.
+	FailedLogins int `db:"failed_logins"`
+}
+
+// HumanAttachmentSize возвращает общий размер вложений в удобном для чтения виде.
+func (s SystemStats) HumanAttachmentSize() string {
+	return humanSize(s.AttachmentBytes)
+}
+
+// ListUsers возвращает пользователей, адрес которых содержит search (без учёта регистра),
+// в порядке регистрации. Пустой search возвращает всех.
+func ListUsers(db *sqlx.DB, search string, limit, offset int) ([]UserSummary, error) {
+	var users []UserSummary
+	query := `SELECT u.id, u.email, u.role, u.verified_at, u.disabled_at, u.last_login_at,
+(SELECT COUNT(*) FROM notes n WHERE n.user_id = u.id AND n.deleted_at IS NULL) AS note_count
+FROM users u WHERE $1 = '' OR STRPOS(LOWER(u.email), LOWER($1)) > 0
+ORDER BY u.id LIMIT $2 OFFSET $3`
+	err := db.Select(&users, query, search, limit, offset)
+	return users, err
+}
+
+// GetSystemStats считает пользователей, заметки, вложения, действующие сессии
+// и неудачные попытки входа после failedSince.
+func GetSystemStats(db *sqlx.DB, failedSince time.Time) (SystemStats, error) {
+	var stats SystemStats
+	query := `SELECT
+(SELECT COUNT(*) FROM users) AS users,
+(SELECT COUNT(*) FROM users WHERE role='admin') AS admins,
+(SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL) AS disabled_users,
+(SELECT COUNT(*) FROM notes WHERE deleted_at IS NULL) AS notes,
+(SELECT COUNT(*) FROM notes WHERE deleted_at IS NOT NULL) AS trashed_notes,
+(SELECT COUNT(*) FROM attachments WHERE note_id IS NOT NULL) AS attachments,
+(SELECT COALESCE(SUM(size_bytes), 0) FROM attachments WHERE note_id IS NOT NULL) AS attachment_bytes,
+(SELECT COUNT(*) FROM sessions WHERE user_id IS NOT NULL AND expires_at > $1) AS active_sessions,
+(SELECT COUNT(*) FROM login_attempts WHERE NOT succeeded AND created_at > $2) AS failed_logins`
+	err := db.Get(&stats, query, time.Now(), failedSince)
+	return stats, err
+}
+
+// PromoteFirstAdmin назначает администратором пользователя с подтверждённым адресом email,
+// если в приложении ещё нет ни одного администратора. Возвращает true, если роль изменилась.
+func PromoteFirstAdmin(db *sqlx.DB, userID int, email string) (bool, error) {
+	query := `UPDATE users SET role='admin'
+WHERE id=$1 AND LOWER(email)=LOWER($2) AND verified_at IS NOT NULL
+AND NOT EXISTS (SELECT 1 FROM users WHERE role='admin')`
+	res, err := db.Exec(query, userID, email)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	return updated > 0, err
+}
+
+// ensureNotLastAdmin возвращает ErrLastAdmin, если userID — единственный действующий администратор.
+// Строки пользователя и всех действующих администраторов блокируются до конца транзакции в порядке id,
+// поэтому два администратора не смогут одновременно отключить или удалить друг друга.
+func ensureNotLastAdmin(tx *sqlx.Tx, userID int) error {
+	var rows []struct {
+		ID          int  `db:"id"`
+		ActiveAdmin bool `db:"active_admin"`
+	}
+	query := `SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users
+WHERE id=$1 OR (role='admin' AND disabled_at IS NULL) ORDER BY id FOR UPDATE`
+	if err := tx.Select(&rows, query, userID); err != nil {
+		return err
+	}
+
+	target, others := false, 0
+	for _, row := range rows {
+		switch {
+		case row.ID == userID:
+			target = row.ActiveAdmin
+		case row.ActiveAdmin:
+			others++
+		}
+	}
+	if target && others == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// updateUserAndEndSessions выполняет изменение пользователя userID и завершает все его сессии
+// в одной транзакции. Если revokeTokens, токены API пользователя тоже удаляются.
+// Последнего действующего администратора изменить нельзя: возвращается ErrLastAdmin.
+func updateUserAndEndSessions(db *sqlx.DB, userID int, revokeTokens bool, query string, args ...any) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := ensureNotLastAdmin(tx, userID); err != nil {
+		return false, err
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil || updated == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id=$1`, userID); err != nil {
+		return false, err
+	}
+	if revokeTokens {
+		if _, err := tx.Exec(`DELETE FROM api_tokens WHERE user_id=$1`, userID); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// DisableUser отключает аккаунт и завершает все его сессии. Токены API не удаляются: пока аккаунт
+// отключён, они не принимаются, а после включения снова действуют. Возвращает false, если пользователя нет.
+func DisableUser(db *sqlx.DB, userID int) (bool, error) {
+	return updateUserAndEndSessions(db, userID, false, `UPDATE users SET disabled_at=$1 WHERE id=$2`, time.Now(), userID)
+}
+
+// EnableUser снова разрешает вход в отключённый аккаунт.
+func EnableUser(db *sqlx.DB, userID int) (bool, error) {
+	res, err := db.Exec(`UPDATE users SET disabled_at=NULL WHERE id=$1`, userID)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	return updated > 0, err
+}
+
+// ForcePasswordReset стирает пароль пользователя, завершает все его сессии и отзывает токены API:
+// войти по паролю можно будет только после сброса по ссылке из письма.
+func ForcePasswordReset(db *sqlx.DB, userID int) (bool, error) {
+	return updateUserAndEndSessions(db, userID, true, `UPDATE users SET password='' WHERE id=$1`, userID)
+}
+
+// DeleteUser удаляет пользователя вместе с его заметками, токенами и сессиями.
+// Содержимое вложений удалённых заметок затем удаляет фоновая задача.
+// Последнего действующего администратора удалить нельзя: возвращается ErrLastAdmin.
+func DeleteUser(db *sqlx.DB, userID int) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := ensureNotLastAdmin(tx, userID); err != nil {
+		return false, err
+	}
+
+	// Блокнот нельзя удалить, пока в нём есть заметки (notes.notebook_id — ON DELETE RESTRICT),
+	// а каскадное удаление от users не задаёт порядок, поэтому сначала удаляем заметки, затем блокноты.
+	if _, err := tx.Exec(`DELETE FROM notes WHERE user_id=$1`, userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM notebooks WHERE user_id=$1`, userID); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`DELETE FROM users WHERE id=$1`, userID)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
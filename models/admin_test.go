@@ -0,0 +1,299 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	lastLogin := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "email", "role", "verified_at", "disabled_at", "last_login_at", "note_count"}).
+		AddRow(1, "admin@example.com", RoleAdmin, lastLogin, nil, lastLogin, 12).
+		AddRow(2, "bob@example.com", RoleUser, nil, nil, nil, 0)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users u WHERE $1 = '' OR STRPOS(LOWER(u.email), LOWER($1)) > 0
+ORDER BY u.id LIMIT $2 OFFSET $3`)).
+		WithArgs("example", 50, 0).
+		WillReturnRows(rows)
+
+	users, err := ListUsers(sqlxDB, "example", 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, 12, users[0].NoteCount)
+	assert.Equal(t, RoleAdmin, users[0].Role)
+	assert.Nil(t, users[1].LastLoginAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSystemStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	since := time.Now().Add(-24 * time.Hour)
+	rows := sqlmock.NewRows([]string{
+		"users", "admins", "disabled_users", "notes", "trashed_notes",
+		"attachments", "attachment_bytes", "active_sessions", "failed_logins",
+	}).AddRow(10, 1, 2, 150, 5, 3, 1536, 7, 4)
+	mock.ExpectQuery(regexp.QuoteMeta(`(SELECT COUNT(*) FROM login_attempts WHERE NOT succeeded AND created_at > $2)`)).
+		WithArgs(sqlmock.AnyArg(), since).
+		WillReturnRows(rows)
+
+	stats, err := GetSystemStats(sqlxDB, since)
+	assert.NoError(t, err)
+	assert.Equal(t, SystemStats{
+		Users: 10, Admins: 1, DisabledUsers: 2, Notes: 150, TrashedNotes: 5,
+		Attachments: 3, AttachmentBytes: 1536, ActiveSessions: 7, FailedLogins: 4,
+	}, stats)
+	assert.Equal(t, "1.5 KB", stats.HumanAttachmentSize())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPromoteFirstAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	query := regexp.QuoteMeta(`UPDATE users SET role='admin'
+WHERE id=$1 AND LOWER(email)=LOWER($2) AND verified_at IS NOT NULL
+AND NOT EXISTS (SELECT 1 FROM users WHERE role='admin')`)
+	mock.ExpectExec(query).WithArgs(1, "admin@example.com").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(1, "admin@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	promoted, err := PromoteFirstAdmin(sqlxDB, 1, "admin@example.com")
+	assert.NoError(t, err)
+	assert.True(t, promoted)
+
+	promoted, err = PromoteFirstAdmin(sqlxDB, 1, "admin@example.com")
+	assert.NoError(t, err)
+	assert.False(t, promoted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisableUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users
+WHERE id=$1 OR (role='admin' AND disabled_at IS NULL) ORDER BY id FOR UPDATE`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(1, true).AddRow(2, false))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at=$1 WHERE id=$2`)).
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	disabled, err := DisableUser(sqlxDB, 2)
+	assert.NoError(t, err)
+	assert.True(t, disabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisableUser_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users
+WHERE id=$1 OR (role='admin' AND disabled_at IS NULL) ORDER BY id FOR UPDATE`)).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(1, true).AddRow(99, false))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at=$1 WHERE id=$2`)).
+		WithArgs(sqlmock.AnyArg(), 99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	disabled, err := DisableUser(sqlxDB, 99)
+	assert.NoError(t, err)
+	assert.False(t, disabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisableUser_LastAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(1, true))
+	mock.ExpectRollback()
+
+	disabled, err := DisableUser(sqlxDB, 1)
+	assert.ErrorIs(t, err, ErrLastAdmin)
+	assert.False(t, disabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_LastAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	// Второй администратор отключён и не считается.
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(3, true))
+	mock.ExpectRollback()
+
+	deleted, err := DeleteUser(sqlxDB, 3)
+	assert.ErrorIs(t, err, ErrLastAdmin)
+	assert.False(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnableUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at=NULL WHERE id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	enabled, err := EnableUser(sqlxDB, 2)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForcePasswordReset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users
+WHERE id=$1 OR (role='admin' AND disabled_at IS NULL) ORDER BY id FOR UPDATE`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(1, true).AddRow(2, false))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET password='' WHERE id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM api_tokens WHERE user_id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	reset, err := ForcePasswordReset(sqlxDB, 2)
+	assert.NoError(t, err)
+	assert.True(t, reset)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users
+WHERE id=$1 OR (role='admin' AND disabled_at IS NULL) ORDER BY id FOR UPDATE`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(1, true).AddRow(2, false))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE user_id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notebooks WHERE user_id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE id=$1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := DeleteUser(sqlxDB, 2)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, role='admin' AND disabled_at IS NULL AS active_admin FROM users
+WHERE id=$1 OR (role='admin' AND disabled_at IS NULL) ORDER BY id FOR UPDATE`)).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active_admin"}).AddRow(1, true).AddRow(99, false))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE user_id=$1`)).
+		WithArgs(99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notebooks WHERE user_id=$1`)).
+		WithArgs(99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE id=$1`)).
+		WithArgs(99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	deleted, err := DeleteUser(sqlxDB, 99)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
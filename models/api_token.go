@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	apiTokenPrefix      = "nwa_"
+	apiTokenBytes       = 32
+	apiTokenDisplayChar = 12
+)
+
+type APIToken struct {
+	ID          int        `db:"id"`
+	UserID      int        `db:"user_id"`
+	Name        string     `db:"name"`
+	TokenPrefix string     `db:"token_prefix"`
+	TokenHash   string     `db:"token_hash"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LastUsedAt  *time.Time `db:"last_used_at"`
+	ExpiresAt   *time.Time `db:"expires_at"`
+}
+
+// GenerateAPIToken создаёт новый токен и возвращает его открытое значение.
+// В базе хранится только хеш, поэтому показать токен можно лишь один раз.
+func GenerateAPIToken() (string, error) {
+	token, err := generateToken(apiTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + token, nil
+}
+
+func (t *APIToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// CreateAPIToken сохраняет токен, вычисляя его хеш и видимый префикс из открытого значения.
+func (t *APIToken) CreateAPIToken(db *sqlx.DB, plain string) error {
+	t.TokenHash = HashToken(plain)
+	t.TokenPrefix = plain[:apiTokenDisplayChar]
+
+	query := `INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return db.QueryRowx(query, t.UserID, t.Name, t.TokenPrefix, t.TokenHash, t.ExpiresAt).
+		Scan(&t.ID, &t.CreatedAt)
+}
+
+func GetAPITokensByUser(db *sqlx.DB, userID int) ([]APIToken, error) {
+	var tokens []APIToken
+	query := `SELECT id, user_id, name, token_prefix, token_hash, created_at, last_used_at, expires_at
+FROM api_tokens WHERE user_id=$1 ORDER BY created_at DESC`
+	err := db.Select(&tokens, query, userID)
+	return tokens, err
+}
+
+// GetAPITokenByHash находит токен по хешу. Токены отключённых пользователей не находятся.
+func GetAPITokenByHash(db *sqlx.DB, hash string) (*APIToken, error) {
+	var token APIToken
+	query := `SELECT t.id, t.user_id, t.name, t.token_prefix, t.token_hash, t.created_at, t.last_used_at, t.expires_at
+FROM api_tokens t JOIN users u ON u.id = t.user_id WHERE t.token_hash=$1 AND u.disabled_at IS NULL`
+
+	err := db.Get(&token, query, hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (t *APIToken) TouchAPIToken(db *sqlx.DB) error {
+	now := time.Now()
+	query := `UPDATE api_tokens SET last_used_at=$1 WHERE id=$2`
+	if _, err := db.Exec(query, now, t.ID); err != nil {
+		return err
+	}
+	t.LastUsedAt = &now
+	return nil
+}
+
+// DeleteAPIToken отзывает токен. Возвращает false, если токен не найден у пользователя.
+func DeleteAPIToken(db *sqlx.DB, id, userID int) (bool, error) {
+	query := `DELETE FROM api_tokens WHERE id=$1 AND user_id=$2`
+	res, err := db.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAPIToken(t *testing.T) {
+	first, err := GenerateAPIToken()
+	assert.NoError(t, err)
+	second, err := GenerateAPIToken()
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, apiTokenPrefix))
+	assert.NotEqual(t, first, second)
+	assert.Len(t, HashToken(first), 64)
+	assert.Equal(t, HashToken(first), HashToken(first))
+}
+
+func TestAPIToken_IsExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.False(t, (&APIToken{}).IsExpired(now))
+	assert.True(t, (&APIToken{ExpiresAt: &past}).IsExpired(now))
+	assert.False(t, (&APIToken{ExpiresAt: &future}).IsExpired(now))
+}
+
+func TestAPIToken_CreateAPIToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	plain := "nwa_abcdefghijklmnopqrstuvwxyz"
+	token := &APIToken{UserID: 1, Name: "cron"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`)).
+		WithArgs(1, "cron", "nwa_abcdefgh", HashToken(plain), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+
+	err = token.CreateAPIToken(sqlxDB, plain)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, token.ID)
+	assert.Equal(t, "nwa_abcdefgh", token.TokenPrefix)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAPITokenByHash_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM api_tokens t JOIN users u ON u.id = t.user_id
+WHERE t.token_hash=$1 AND u.disabled_at IS NULL`)).
+		WithArgs("hash").
+		WillReturnError(sql.ErrNoRows)
+
+	token, err := GetAPITokenByHash(sqlxDB, "hash")
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteAPIToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM api_tokens WHERE id=$1 AND user_id=$2`)).
+		WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deleted, err := DeleteAPIToken(sqlxDB, 3, 1)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	MaxAttachmentSize           = 25 << 20
+	MaxAttachmentFilenameLength = 255
+	attachmentKeyBytes          = 18
+)
+
+var ErrAttachmentTooLarge = fmt.Errorf("attachment exceeds the %d MB limit", MaxAttachmentSize>>20)
+
+// inlineContentTypes можно показывать прямо в браузере, остальное отдаётся на скачивание.
+var inlineContentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+type Attachment struct {
+	ID          int       `db:"id" json:"id"`
+	NoteID      int       `db:"note_id" json:"noteId"`
+	Filename    string    `db:"filename" json:"filename"`
+	ContentType string    `db:"content_type" json:"contentType"`
+	SizeBytes   int64     `db:"size_bytes" json:"sizeBytes"`
+	StorageKey  string    `db:"storage_key" json:"-"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+}
+
+// NewAttachmentKey возвращает случайный ключ в хранилище для нового вложения заметки.
+func NewAttachmentKey(noteID int) (string, error) {
+	token, err := generateToken(attachmentKeyBytes)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("notes/%d/%s", noteID, token), nil
+}
+
+// CleanAttachmentFilename оставляет от имени файла только последний сегмент пути
+// без управляющих символов и обрезает его до MaxAttachmentFilenameLength.
+func CleanAttachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	for utf8.RuneCountInString(name) > MaxAttachmentFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func (a *Attachment) IsInline() bool {
+	mediaType, _, err := mime.ParseMediaType(a.ContentType)
+	return err == nil && inlineContentTypes[mediaType]
+}
+
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/") && a.IsInline()
+}
+
+// HumanSize возвращает размер в удобном для чтения виде, например "1.5 MB".
+func (a *Attachment) HumanSize() string {
+	return humanSize(a.SizeBytes)
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+func (a *Attachment) CreateAttachment(db *sqlx.DB) error {
+	query := `INSERT INTO attachments (note_id, filename, content_type, size_bytes, storage_key)
+VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return db.QueryRowx(query, a.NoteID, a.Filename, a.ContentType, a.SizeBytes, a.StorageKey).
+		Scan(&a.ID, &a.CreatedAt)
+}
+
+func GetAttachmentsByNote(db *sqlx.DB, noteID int) ([]Attachment, error) {
+	var attachments []Attachment
+	query := `SELECT id, note_id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments WHERE note_id=$1 ORDER BY created_at, id`
+	err := db.Select(&attachments, query, noteID)
+	return attachments, err
+}
+
+func GetAttachment(db *sqlx.DB, id, noteID int) (*Attachment, error) {
+	var attachment Attachment
+	query := `SELECT id, note_id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments WHERE id=$1 AND note_id=$2`
+
+	err := db.Get(&attachment, query, id, noteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (a *Attachment) DeleteAttachment(db *sqlx.DB) error {
+	query := `DELETE FROM attachments WHERE id=$1`
+	_, err := db.Exec(query, a.ID)
+	return err
+}
+
+// GetOrphanedAttachments возвращает вложения окончательно удалённых заметок,
+// содержимое которых ещё нужно удалить из хранилища.
+func GetOrphanedAttachments(db *sqlx.DB, limit int) ([]Attachment, error) {
+	var attachments []Attachment
+	query := `SELECT id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments WHERE note_id IS NULL ORDER BY id LIMIT $1`
+	err := db.Select(&attachments, query, limit)
+	return attachments, err
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanAttachmentFilename(t *testing.T) {
+	assert.Equal(t, "report.pdf", CleanAttachmentFilename("report.pdf"))
+	assert.Equal(t, "passwd", CleanAttachmentFilename("../../etc/passwd"))
+	assert.Equal(t, "shot.png", CleanAttachmentFilename(`C:\Users\me\shot.png`))
+	assert.Equal(t, "ab.txt", CleanAttachmentFilename("a\"\nb.txt"))
+	assert.Equal(t, "attachment", CleanAttachmentFilename(""))
+	assert.Equal(t, MaxAttachmentFilenameLength, len([]rune(CleanAttachmentFilename(strings.Repeat("я", 300)))))
+}
+
+func TestNewAttachmentKey(t *testing.T) {
+	first, err := NewAttachmentKey(5)
+	assert.NoError(t, err)
+	second, err := NewAttachmentKey(5)
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "notes/5/"))
+	assert.NotEqual(t, first, second)
+}
+
+func TestAttachment_HumanSize(t *testing.T) {
+	assert.Equal(t, "512 B", (&Attachment{SizeBytes: 512}).HumanSize())
+	assert.Equal(t, "1.5 KB", (&Attachment{SizeBytes: 1536}).HumanSize())
+	assert.Equal(t, "25.0 MB", (&Attachment{SizeBytes: MaxAttachmentSize}).HumanSize())
+}
+
+func TestAttachment_IsInline(t *testing.T) {
+	assert.True(t, (&Attachment{ContentType: "image/png"}).IsImage())
+	assert.True(t, (&Attachment{ContentType: "application/pdf"}).IsInline())
+	assert.True(t, (&Attachment{ContentType: "text/plain; charset=utf-8"}).IsInline())
+	assert.False(t, (&Attachment{ContentType: "text/html"}).IsInline())
+	assert.False(t, (&Attachment{ContentType: "image/svg+xml"}).IsImage())
+}
+
+func TestAttachment_CreateAttachment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	attachment := &Attachment{NoteID: 1, Filename: "log.txt", ContentType: "text/plain", SizeBytes: 10, StorageKey: "notes/1/k"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO attachments (note_id, filename, content_type, size_bytes, storage_key)`)).
+		WithArgs(1, "log.txt", "text/plain", int64(10), "notes/1/k").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+
+	err = attachment.CreateAttachment(sqlxDB)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attachment.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAttachment_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE id=$1 AND note_id=$2`)).
+		WithArgs(3, 1).
+		WillReturnError(sql.ErrNoRows)
+
+	attachment, err := GetAttachment(sqlxDB, 3, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, attachment)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Действия, которые записываются в журнал аудита.
+const (
+	AuditLogin         = "login"
+	AuditLoginFailed   = "login.failed"
+	AuditRegister      = "user.register"
+	AuditNoteCreate    = "note.create"
+	AuditNoteUpdate    = "note.update"
+	AuditNoteDelete    = "note.delete" // перемещение в корзину
+	AuditNoteRestore   = "note.restore"
+	AuditNotePurge     = "note.purge" // окончательное удаление
+	AuditTrashEmpty    = "trash.empty"
+	AuditNoteShare     = "note.share"
+	AuditNoteUnshare   = "note.unshare"
+	AuditLinkCreate    = "link.create"
+	AuditLinkRevoke    = "link.revoke"
+	AuditUserDisable   = "user.disable"
+	AuditUserEnable    = "user.enable"
+	AuditPasswordReset = "user.password_reset" // сброс пароля администратором
+	AuditUserDelete    = "user.delete"
+)
+
+// AuditActions перечисляет все действия журнала в том порядке, в котором они показываются в фильтре.
+var AuditActions = []string{
+	AuditLogin, AuditLoginFailed, AuditRegister,
+	AuditNoteCreate, AuditNoteUpdate, AuditNoteDelete, AuditNoteRestore, AuditNotePurge, AuditTrashEmpty,
+	AuditNoteShare, AuditNoteUnshare, AuditLinkCreate, AuditLinkRevoke,
+	AuditUserDisable, AuditUserEnable, AuditPasswordReset, AuditUserDelete,
+}
+
+// Типы объектов, над которыми выполняется действие.
+const (
+	AuditTargetUser = "user"
+	AuditTargetNote = "note"
+)
+
+// MaxAuditExport — наибольшее количество событий в одной выгрузке журнала.
+const MaxAuditExport = 10000
+
+// AuditEvent — запись журнала аудита: кто, откуда и когда выполнил действие и над чем.
+type AuditEvent struct {
+	ID int `db:"id" json:"id"`
+	// ActorID пуст, если действие выполнил неизвестный пользователь (например, неудачный вход)
+	// или если пользователь удалён.
+	ActorID    *int      `db:"actor_id" json:"actorId"`
+	ActorEmail string    `db:"actor_email" json:"actorEmail"`
+	Action     string    `db:"action" json:"action"`
+	TargetType string    `db:"target_type" json:"targetType,omitempty"`
+	TargetID   *int      `db:"target_id" json:"targetId,omitempty"`
+	Details    string    `db:"details" json:"details,omitempty"`
+	IP         string    `db:"ip" json:"ip"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// AuditFilter отбирает события журнала; пустые поля не ограничивают выборку.
+type AuditFilter struct {
+	// UserID — события, которые пользователь выполнил сам или которые касаются его аккаунта.
+	UserID int
+	// Actor — часть адреса того, кто выполнил действие, без учёта регистра.
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   int
+	From       time.Time
+	To         time.Time
+}
+
+// ByUser сообщает, выполнил ли действие пользователь userID.
+func (e *AuditEvent) ByUser(userID int) bool {
+	return e.ActorID != nil && *e.ActorID == userID
+}
+
+// RecordAuditEvent сохраняет событие. Если ActorEmail пуст, в запись копируется текущий адрес ActorID.
+func (e *AuditEvent) RecordAuditEvent(db *sqlx.DB) error {
+	e.CreatedAt = time.Now()
+
+	query := `INSERT INTO audit_events (actor_id, actor_email, action, target_type, target_id, details, ip, created_at)
+VALUES ($1, COALESCE(NULLIF($2, ''), (SELECT email FROM users WHERE id=$1), ''), $3, $4, $5, $6, $7, $8)
+RETURNING id, actor_email`
+	return db.QueryRowx(query, e.ActorID, e.ActorEmail, e.Action, e.TargetType, e.TargetID, e.Details, e.IP,
+		e.CreatedAt).Scan(&e.ID, &e.ActorEmail)
+}
+
+// where возвращает условия выборки и их аргументы.
+func (f AuditFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	next := func(arg interface{}) string {
+		args = append(args, arg)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.UserID != 0 {
+		p := next(f.UserID)
+		conditions = append(conditions, `(actor_id=`+p+` OR (target_type='user' AND target_id=`+p+`))`)
+	}
+	if f.Actor != "" {
+		conditions = append(conditions, `STRPOS(LOWER(actor_email), LOWER(`+next(f.Actor)+`)) > 0`)
+	}
+	if f.Action != "" {
+		conditions = append(conditions, `action=`+next(f.Action))
+	}
+	if f.TargetType != "" {
+		conditions = append(conditions, `target_type=`+next(f.TargetType))
+	}
+	if f.TargetID != 0 {
+		conditions = append(conditions, `target_id=`+next(f.TargetID))
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, `created_at >= `+next(f.From))
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, `created_at < `+next(f.To))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND "), args
+}
+
+// SearchAuditEvents возвращает события, подходящие под filter, новые первыми.
+func SearchAuditEvents(db *sqlx.DB, filter AuditFilter, limit, offset int) ([]AuditEvent, error) {
+	where, args := filter.where()
+	args = append(args, limit, offset)
+	query := `SELECT id, actor_id, actor_email, action, target_type, target_id, details, ip, created_at
+FROM audit_events` + where + ` ORDER BY created_at DESC, id DESC
+LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	var events []AuditEvent
+	err := db.Select(&events, query, args...)
+	return events, err
+}
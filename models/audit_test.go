@@ -0,0 +1,104 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEvent_RecordAuditEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	actorID, noteID := 1, 5
+	event := &AuditEvent{
+		ActorID:    &actorID,
+		Action:     AuditNoteDelete,
+		TargetType: AuditTargetNote,
+		TargetID:   &noteID,
+		Details:    "Groceries",
+		IP:         "192.0.2.1",
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO audit_events
+(actor_id, actor_email, action, target_type, target_id, details, ip, created_at)
+VALUES ($1, COALESCE(NULLIF($2, ''), (SELECT email FROM users WHERE id=$1), ''), $3, $4, $5, $6, $7, $8)
+RETURNING id, actor_email`)).
+		WithArgs(&actorID, "", AuditNoteDelete, AuditTargetNote, &noteID, "Groceries", "192.0.2.1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_email"}).AddRow(3, "alice@example.com"))
+
+	err = event.RecordAuditEvent(sqlxDB)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, event.ID)
+	assert.Equal(t, "alice@example.com", event.ActorEmail)
+	assert.False(t, event.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchAuditEvents_NoFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{
+		"id", "actor_id", "actor_email", "action", "target_type", "target_id", "details", "ip", "created_at",
+	}).AddRow(2, nil, "mallory@example.com", AuditLoginFailed, AuditTargetUser, 1, "", "192.0.2.9", time.Now())
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_events ORDER BY created_at DESC, id DESC
+LIMIT $1 OFFSET $2`)).
+		WithArgs(50, 0).
+		WillReturnRows(rows)
+
+	events, err := SearchAuditEvents(sqlxDB, AuditFilter{}, 50, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Nil(t, events[0].ActorID)
+		assert.Equal(t, AuditLoginFailed, events[0].Action)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchAuditEvents_Filter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	filter := AuditFilter{
+		UserID:     1,
+		Actor:      "alice",
+		Action:     AuditNoteDelete,
+		TargetType: AuditTargetNote,
+		TargetID:   5,
+		From:       from,
+		To:         to,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_events WHERE (actor_id=$1 OR (target_type='user' AND target_id=$1))
+AND STRPOS(LOWER(actor_email), LOWER($2)) > 0 AND action=$3 AND target_type=$4 AND target_id=$5
+AND created_at >= $6 AND created_at < $7 ORDER BY created_at DESC, id DESC LIMIT $8 OFFSET $9`)).
+		WithArgs(1, "alice", AuditNoteDelete, AuditTargetNote, 5, from, to, 20, 40).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	events, err := SearchAuditEvents(sqlxDB, filter, 20, 40)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
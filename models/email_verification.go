@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	emailVerificationTokenBytes = 32
+	// EmailVerificationLifetime — срок действия ссылки для подтверждения адреса.
+	EmailVerificationLifetime = 48 * time.Hour
+)
+
+var ErrEmailVerificationInvalid = errors.New("verification link is invalid or has expired")
+
+// EmailVerification — ссылка для подтверждения адреса электронной почты. В базе хранится только хеш токена.
+type EmailVerification struct {
+	ID        int       `db:"id"`
+	UserID    int       `db:"user_id"`
+	TokenHash string    `db:"token_hash"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// GenerateEmailVerificationToken возвращает случайный токен для ссылки подтверждения адреса.
+func GenerateEmailVerificationToken() (string, error) {
+	return generateToken(emailVerificationTokenBytes)
+}
+
+// CreateEmailVerification сохраняет ссылку подтверждения, вычисляя хеш из открытого токена.
+// Ссылки, отправленные пользователю раньше, отзываются: действует только последняя.
+func (ev *EmailVerification) CreateEmailVerification(db *sqlx.DB, plain string) error {
+	ev.TokenHash = HashToken(plain)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM email_verifications WHERE user_id=$1`, ev.UserID); err != nil {
+		return err
+	}
+	query := `INSERT INTO email_verifications (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+RETURNING id, created_at`
+	if err := tx.QueryRowx(query, ev.UserID, ev.TokenHash, ev.ExpiresAt).Scan(&ev.ID, &ev.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// VerifyEmail подтверждает адрес пользователя по открытому токену и удаляет все его ссылки подтверждения.
+// Возвращает идентификатор пользователя или ErrEmailVerificationInvalid, если ссылка не найдена или истекла.
+func VerifyEmail(db *sqlx.DB, plain string) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	var userID int
+	err = tx.QueryRowx(`DELETE FROM email_verifications WHERE token_hash=$1 AND expires_at > $2 RETURNING user_id`,
+		HashToken(plain), now).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrEmailVerificationInvalid
+		}
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET verified_at=$1 WHERE id=$2 AND verified_at IS NULL`, now, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM email_verifications WHERE user_id=$1`, userID); err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
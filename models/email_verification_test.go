@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification_CreateEmailVerification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	expires := time.Now().Add(EmailVerificationLifetime)
+	verification := &EmailVerification{UserID: 1, ExpiresAt: expires}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM email_verifications WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO email_verifications (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3) RETURNING id, created_at`)).
+		WithArgs(1, HashToken("plain"), expires).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+	mock.ExpectCommit()
+
+	err = verification.CreateEmailVerification(sqlxDB, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, verification.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM email_verifications WHERE token_hash=$1 AND expires_at > $2
+RETURNING user_id`)).
+		WithArgs(HashToken("plain"), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET verified_at=$1 WHERE id=$2 AND verified_at IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM email_verifications WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	userID, err := VerifyEmail(sqlxDB, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyEmail_Invalid(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM email_verifications WHERE token_hash=$1`)).
+		WithArgs(HashToken("expired"), sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = VerifyEmail(sqlxDB, "expired")
+	assert.True(t, errors.Is(err, ErrEmailVerificationInvalid))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
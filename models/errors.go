@@ -0,0 +1,15 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const uniqueViolationCode = "23505"
+
+// isUniqueViolation сообщает, что запрос нарушил ограничение уникальности.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetUserByIdentity возвращает пользователя, связанного с учётной записью внешнего провайдера, или nil.
+func GetUserByIdentity(db *sqlx.DB, issuer, subject string) (*User, error) {
+	var user User
+	query := `SELECT u.id, u.email, u.password, u.verified_at, u.totp_secret, u.totp_enabled_at, u.role, u.disabled_at
+FROM users u JOIN user_identities i ON i.user_id = u.id WHERE i.issuer=$1 AND i.subject=$2`
+
+	err := db.Get(&user, query, issuer, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkIdentity связывает учётную запись внешнего провайдера с существующим пользователем.
+func LinkIdentity(db *sqlx.DB, userID int, issuer, subject string) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)
+ON CONFLICT (issuer, subject) DO NOTHING`
+	_, err := db.Exec(query, userID, issuer, subject)
+	return err
+}
+
+// CreateUserWithIdentity создаёт пользователя при первом входе через внешний провайдер.
+// Адрес считается подтверждённым провайдером, пароля у пользователя нет.
+// Если адрес уже занят, возвращает ErrDuplicateEmail.
+func CreateUserWithIdentity(db *sqlx.DB, email, issuer, subject string) (*User, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	user := &User{Email: email, VerifiedAt: &now, Role: RoleUser}
+	err = tx.QueryRowx(`INSERT INTO users (email, password, verified_at) VALUES ($1, '', $2) RETURNING id`,
+		email, now).Scan(&user.ID)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)`,
+		user.ID, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := createDefaultNotebook(tx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, tx.Commit()
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUserByIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	columns := []string{"id", "email", "password", "verified_at", "totp_secret", "totp_enabled_at", "role", "disabled_at"}
+	rows := sqlmock.NewRows(columns).AddRow(1, "alice@example.com", "", nil, nil, nil, RoleUser, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users u JOIN user_identities i ON i.user_id = u.id
+WHERE i.issuer=$1 AND i.subject=$2`)).
+		WithArgs("https://idp.example.com", "user-42").
+		WillReturnRows(rows)
+
+	user, err := GetUserByIdentity(sqlxDB, "https://idp.example.com", "user-42")
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, 1, user.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserByIdentity_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN user_identities i`)).
+		WithArgs("https://idp.example.com", "unknown").
+		WillReturnError(sql.ErrNoRows)
+
+	user, err := GetUserByIdentity(sqlxDB, "https://idp.example.com", "unknown")
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLinkIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)
+ON CONFLICT (issuer, subject) DO NOTHING`)).
+		WithArgs(1, "https://idp.example.com", "user-42").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, LinkIdentity(sqlxDB, 1, "https://idp.example.com", "user-42"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUserWithIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (email, password, verified_at) VALUES ($1, '', $2)`)).
+		WithArgs("alice@example.com", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)`)).
+		WithArgs(5, "https://idp.example.com", "user-42").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO notebooks (user_id, name, is_default) VALUES ($1, $2, TRUE)`)).
+		WithArgs(5, DefaultNotebookName).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	user, err := CreateUserWithIdentity(sqlxDB, "alice@example.com", "https://idp.example.com", "user-42")
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, 5, user.ID)
+		assert.True(t, user.IsVerified())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUserWithIdentity_DuplicateEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (email, password, verified_at) VALUES ($1, '', $2)`)).
+		WithArgs("Alice@Example.com", sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: uniqueViolationCode})
+	mock.ExpectRollback()
+
+	user, err := CreateUserWithIdentity(sqlxDB, "Alice@Example.com", "https://idp.example.com", "user-42")
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LoginAttempt — попытка входа по паролю или коду второго фактора.
+// Email сохраняется в нижнем регистре, даже если пользователя с таким адресом нет.
+type LoginAttempt struct {
+	ID        int       `db:"id"`
+	Email     string    `db:"email"`
+	UserID    *int      `db:"user_id"`
+	IP        string    `db:"ip"`
+	Succeeded bool      `db:"succeeded"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// LoginFailures — количество неудачных попыток и время последней из них.
+type LoginFailures struct {
+	Count int        `db:"failures"`
+	Last  *time.Time `db:"last_failure"`
+}
+
+// LoginThrottle — правило ограничения попыток входа. Первые FreeAttempts неудач не замедляют вход,
+// затем пауза после каждой неудачи удваивается начиная с BaseDelay (но не больше MaxDelay),
+// а после LockoutAfter неудач вход блокируется на LockoutDuration. Учитываются неудачи за Window.
+type LoginThrottle struct {
+	Window          time.Duration
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+}
+
+// Delay возвращает паузу, которую нужно выдержать после последней из failures неудачных попыток.
+func (lt LoginThrottle) Delay(failures int) time.Duration {
+	switch {
+	case failures >= lt.LockoutAfter:
+		return lt.LockoutDuration
+	case failures <= lt.FreeAttempts:
+		return 0
+	}
+
+	delay := lt.BaseDelay
+	for i := lt.FreeAttempts + 1; i < failures && delay < lt.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, lt.MaxDelay)
+}
+
+// RetryAt возвращает момент, раньше которого новая попытка не принимается.
+func (lt LoginThrottle) RetryAt(failures LoginFailures) time.Time {
+	if failures.Last == nil {
+		return time.Time{}
+	}
+	return failures.Last.Add(lt.Delay(failures.Count))
+}
+
+// RecordLoginAttempt сохраняет попытку входа.
+func (la *LoginAttempt) RecordLoginAttempt(db *sqlx.DB) error {
+	la.CreatedAt = time.Now()
+
+	query := `INSERT INTO login_attempts (email, user_id, ip, succeeded, created_at) VALUES ($1, $2, $3, $4, $5)
+RETURNING id`
+	return db.QueryRowx(query, la.Email, la.UserID, la.IP, la.Succeeded, la.CreatedAt).Scan(&la.ID)
+}
+
+// CountAccountFailures возвращает неудачные попытки входа в аккаунт email после since
+// и после последнего успешного входа в него.
+func CountAccountFailures(db *sqlx.DB, email string, since time.Time) (LoginFailures, error) {
+	var failures LoginFailures
+	query := `SELECT COUNT(*) AS failures, MAX(created_at) AS last_failure FROM login_attempts
+WHERE email=$1 AND NOT succeeded AND created_at > $2
+AND created_at > COALESCE((SELECT MAX(created_at) FROM login_attempts WHERE email=$1 AND succeeded), $2)`
+	err := db.Get(&failures, query, email, since)
+	return failures, err
+}
+
+// CountIPFailures возвращает неудачные попытки входа с адреса ip после since, в любые аккаунты.
+func CountIPFailures(db *sqlx.DB, ip string, since time.Time) (LoginFailures, error) {
+	var failures LoginFailures
+	query := `SELECT COUNT(*) AS failures, MAX(created_at) AS last_failure FROM login_attempts
+WHERE ip=$1 AND NOT succeeded AND created_at > $2`
+	err := db.Get(&failures, query, ip, since)
+	return failures, err
+}
+
+// GetFailedLoginAttempts возвращает последние неудачные попытки входа, новые первыми.
+func GetFailedLoginAttempts(db *sqlx.DB, limit int) ([]LoginAttempt, error) {
+	var attempts []LoginAttempt
+	query := `SELECT id, email, user_id, ip, succeeded, created_at FROM login_attempts WHERE NOT succeeded
+ORDER BY created_at DESC, id DESC LIMIT $1`
+	err := db.Select(&attempts, query, limit)
+	return attempts, err
+}
+
+// PurgeLoginAttempts удаляет записи о попытках входа старше before.
+func PurgeLoginAttempts(db *sqlx.DB, before time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM login_attempts WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
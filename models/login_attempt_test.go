@@ -0,0 +1,163 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var testThrottle = LoginThrottle{
+	Window:          24 * time.Hour,
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAfter:    10,
+	LockoutDuration: 15 * time.Minute,
+}
+
+func TestLoginThrottle_Delay(t *testing.T) {
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{8, 16 * time.Second},
+		{9, 30 * time.Second},
+		{10, 15 * time.Minute},
+		{50, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, testThrottle.Delay(tt.failures), "failures %d", tt.failures)
+	}
+}
+
+func TestLoginThrottle_RetryAt(t *testing.T) {
+	assert.True(t, testThrottle.RetryAt(LoginFailures{}).IsZero())
+
+	last := time.Now()
+	retryAt := testThrottle.RetryAt(LoginFailures{Count: 10, Last: &last})
+	assert.Equal(t, last.Add(15*time.Minute), retryAt)
+}
+
+func TestLoginAttempt_RecordLoginAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	userID := 1
+	attempt := &LoginAttempt{Email: "alice@example.com", UserID: &userID, IP: "10.0.0.1"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO login_attempts (email, user_id, ip, succeeded, created_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING id`)).
+		WithArgs("alice@example.com", &userID, "10.0.0.1", false, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	err = attempt.RecordLoginAttempt(sqlxDB)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempt.ID)
+	assert.False(t, attempt.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountAccountFailures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	since := time.Now().Add(-24 * time.Hour)
+	last := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) AS failures, MAX(created_at) AS last_failure FROM login_attempts
+WHERE email=$1 AND NOT succeeded AND created_at > $2
+AND created_at > COALESCE((SELECT MAX(created_at) FROM login_attempts WHERE email=$1 AND succeeded), $2)`)).
+		WithArgs("alice@example.com", since).
+		WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure"}).AddRow(4, last))
+
+	failures, err := CountAccountFailures(sqlxDB, "alice@example.com", since)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, failures.Count)
+	if assert.NotNil(t, failures.Last) {
+		assert.Equal(t, last, *failures.Last)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountIPFailures_None(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	since := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM login_attempts WHERE ip=$1 AND NOT succeeded AND created_at > $2`)).
+		WithArgs("10.0.0.1", since).
+		WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure"}).AddRow(0, nil))
+
+	failures, err := CountIPFailures(sqlxDB, "10.0.0.1", since)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failures.Count)
+	assert.Nil(t, failures.Last)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFailedLoginAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "email", "user_id", "ip", "succeeded", "created_at"}).
+		AddRow(2, "bob@example.com", nil, "10.0.0.2", false, time.Now()).
+		AddRow(1, "alice@example.com", 1, "10.0.0.1", false, time.Now())
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM login_attempts WHERE NOT succeeded
+ORDER BY created_at DESC, id DESC LIMIT $1`)).
+		WithArgs(50).
+		WillReturnRows(rows)
+
+	attempts, err := GetFailedLoginAttempts(sqlxDB, 50)
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 2)
+	assert.Nil(t, attempts[0].UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeLoginAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	before := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM login_attempts WHERE created_at < $1`)).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 7))
+
+	purged, err := PurgeLoginAttempts(sqlxDB, before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
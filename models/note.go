@@ -3,50 +3,216 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Note struct {
-	ID        int       `db:"id"`
-	Title     string    `db:"title"`
-	Content   string    `db:"content"`
-	UserID    int       `db:"user_id"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID         int        `db:"id" json:"id"`
+	Title      string     `db:"title" json:"title"`
+	Content    string     `db:"content" json:"content"`
+	UserID     int        `db:"user_id" json:"userId"`
+	NotebookID int        `db:"notebook_id" json:"notebookId"`
+	Pinned     bool       `db:"pinned" json:"pinned"`
+	Archived   bool       `db:"archived" json:"archived"`
+	Version    int        `db:"version" json:"version"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt  *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+	Tags       []string   `db:"-" json:"tags"`
 }
 
+// NoteFilter задаёт условия выборки заметок пользователя.
+// Archived выбирает архив вместо основного списка.
+type NoteFilter struct {
+	Tag        string
+	NotebookID int
+	Archived   bool
+}
+
+const MaxTitleLength = 255
+
+var (
+	ErrEmptyTitle   = errors.New("title is required")
+	ErrTitleTooLong = errors.New("title is too long")
+	ErrEmptyContent = errors.New("content is required")
+	// ErrVersionConflict означает, что заметку уже изменили после того, как её открыли для редактирования.
+	ErrVersionConflict = errors.New("note was modified by someone else")
+)
+
+// Validate проверяет поля заметки перед сохранением.
+func (n *Note) Validate() error {
+	if strings.TrimSpace(n.Title) == "" {
+		return ErrEmptyTitle
+	}
+	if utf8.RuneCountInString(n.Title) > MaxTitleLength {
+		return ErrTitleTooLong
+	}
+	if strings.TrimSpace(n.Content) == "" {
+		return ErrEmptyContent
+	}
+	return nil
+}
+
+// CreateNote одной транзакцией сохраняет заметку вместе с её первой ревизией и тегами n.Tags.
 func (n *Note) CreateNote(db *sqlx.DB) error {
-	query := `INSERT INTO notes (title, content, user_id) 
-VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
-	return db.QueryRowx(query, n.Title, n.Content, n.UserID).Scan(&n.ID, &n.CreatedAt, &n.UpdatedAt)
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if n.NotebookID == 0 {
+		if n.NotebookID, err = EnsureDefaultNotebook(tx, n.UserID); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO notes (title, content, user_id, notebook_id, pinned, archived) 
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, version`
+	err = tx.QueryRowx(query, n.Title, n.Content, n.UserID, n.NotebookID, n.Pinned, n.Archived).
+		Scan(&n.ID, &n.CreatedAt, &n.UpdatedAt, &n.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := createRevision(tx, n, n.UserID); err != nil {
+		return err
+	}
+	if len(n.Tags) > 0 {
+		if err := setNoteTags(tx, n.ID, n.UserID, n.Tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func (n *Note) UpdateNote(db *sqlx.DB) error {
-	n.UpdatedAt = time.Now()
-	query := `UPDATE notes SET title=:title, content=:content, updated_at=:updated_at 
-             WHERE id=:id`
-	_, err := db.NamedExec(query, n)
-	return err
+// UpdateNote одной транзакцией сохраняет текст, блокнот, закрепление и архив заметки и записывает
+// изменения в историю от имени authorID. Если tags не nil, теги заметки заменяются в той же транзакции.
+// Запись проходит, только если в базе всё ещё версия n.Version, иначе возвращается ErrVersionConflict.
+func (n *Note) UpdateNote(db *sqlx.DB, authorID int, tags []string) error {
+	updatedAt := time.Now()
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `UPDATE notes SET title=$1, content=$2, notebook_id=$3, pinned=$4, archived=$5, updated_at=$6,
+             version=version+1 WHERE id=$7 AND version=$8`
+	res, err := tx.Exec(query, n.Title, n.Content, n.NotebookID, n.Pinned, n.Archived, updatedAt, n.ID, n.Version)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrVersionConflict
+	}
+
+	n.UpdatedAt = updatedAt
+	if err := createRevision(tx, n, authorID); err != nil {
+		return err
+	}
+	// Теги принадлежат владельцу заметки, даже если её редактирует другой пользователь.
+	if tags != nil {
+		if err := setNoteTags(tx, n.ID, n.UserID, tags); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	n.Version++
+	if tags != nil {
+		n.Tags = tags
+	}
+	return nil
 }
 
+// DeleteNote перемещает заметку в корзину. Окончательно удаляет PurgeNote.
 func (n *Note) DeleteNote(db *sqlx.DB) error {
-	query := `DELETE FROM notes WHERE id=:id`
+	now := time.Now()
+	n.DeletedAt = &now
+	query := `UPDATE notes SET deleted_at=:deleted_at WHERE id=:id`
 	_, err := db.NamedExec(query, n)
 	return err
 }
 
-func (n *Note) GetNotesByUser(db *sqlx.DB, userID int) ([]Note, error) {
+// GetNotesByUser возвращает одну страницу заметок пользователя. Страницы листаются по курсору
+// (keyset-пагинация), поэтому порядок стабилен даже при добавлении новых заметок.
+func (n *Note) GetNotesByUser(db *sqlx.DB, userID int, filter NoteFilter, page PageRequest) (*NotePage, error) {
+	if page.Sort == "" {
+		page.Sort = SortUpdated
+	}
+	if page.Limit == 0 {
+		page.Limit = DefaultPageSize
+	}
+	if page.Limit < 0 || page.Limit > MaxPageSize {
+		return nil, ErrInvalidPageSize
+	}
+
+	query := `SELECT id, title, content, user_id, notebook_id, pinned, archived, version, created_at, updated_at
+FROM notes WHERE user_id=$1 AND deleted_at IS NULL AND archived=$2`
+	args := []interface{}{userID, filter.Archived}
+
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		query += ` AND EXISTS (SELECT 1 FROM note_tags nt JOIN tags t ON t.id=nt.tag_id
+WHERE nt.note_id=notes.id AND t.name=$` + strconv.Itoa(len(args)) + `)`
+	}
+
+	if filter.NotebookID != 0 {
+		args = append(args, filter.NotebookID)
+		query += ` AND notebook_id=$` + strconv.Itoa(len(args))
+	}
+
+	column, desc := page.Sort.column()
+	direction, cmp := "ASC", ">"
+	if desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		cursor, key, err := decodeNoteCursor(page.Sort, page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cursor.Pinned, key, cursor.ID)
+		p, k, i := strconv.Itoa(len(args)-2), strconv.Itoa(len(args)-1), strconv.Itoa(len(args))
+		query += ` AND (pinned < $` + p + ` OR (pinned = $` + p + ` AND (` + column + `, id) ` + cmp +
+			` ($` + k + `, $` + i + `)))`
+	}
+
+	// Закреплённые заметки всегда идут первыми; id делает порядок однозначным.
+	args = append(args, page.Limit+1)
+	query += ` ORDER BY pinned DESC, ` + column + ` ` + direction + `, id ` + direction +
+		` LIMIT $` + strconv.Itoa(len(args))
+
 	var notes []Note
-	query := `SELECT id, title, content, created_at, updated_at FROM notes WHERE user_id=$1`
-	err := db.Select(&notes, query, userID)
-	return notes, err
+	if err := db.Select(&notes, query, args...); err != nil {
+		return nil, err
+	}
+
+	result := &NotePage{Notes: notes}
+	if len(notes) > page.Limit {
+		result.Notes = notes[:page.Limit]
+		result.NextCursor = encodeNoteCursor(page.Sort, &result.Notes[page.Limit-1])
+	}
+	return result, nil
 }
 
 func GetNoteByID(db *sqlx.DB, id int) (*Note, error) {
 	var note Note
-	query := `SELECT id, title, content, user_id, created_at, updated_at FROM notes WHERE id=$1`
+	query := `SELECT id, title, content, user_id, notebook_id, pinned, archived, version, created_at, updated_at
+FROM notes WHERE id=$1 AND deleted_at IS NULL`
 
 	err := db.Get(&note, query, id)
 	if err != nil {
@@ -57,3 +223,24 @@ func GetNoteByID(db *sqlx.DB, id int) (*Note, error) {
 	}
 	return &note, err
 }
+
+// SetPinned закрепляет заметку вверху списка или открепляет её. Версия заметки увеличивается,
+// чтобы изменение не затёрла правка, открытая раньше.
+func (n *Note) SetPinned(db *sqlx.DB, pinned bool) error {
+	query := `UPDATE notes SET pinned=$1, version=version+1 WHERE id=$2 RETURNING version`
+	if err := db.QueryRowx(query, pinned, n.ID).Scan(&n.Version); err != nil {
+		return err
+	}
+	n.Pinned = pinned
+	return nil
+}
+
+// SetArchived переносит заметку в архив или возвращает её в основной список, увеличивая версию заметки.
+func (n *Note) SetArchived(db *sqlx.DB, archived bool) error {
+	query := `UPDATE notes SET archived=$1, version=version+1 WHERE id=$2 RETURNING version`
+	if err := db.QueryRowx(query, archived, n.ID).Scan(&n.Version); err != nil {
+		return err
+	}
+	n.Archived = archived
+	return nil
+}
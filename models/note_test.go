@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +13,26 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestNote_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		note    Note
+		wantErr error
+	}{
+		{name: "valid", note: Note{Title: "Title", Content: "Content"}},
+		{name: "empty title", note: Note{Title: "  ", Content: "Content"}, wantErr: ErrEmptyTitle},
+		{name: "long title", note: Note{Title: strings.Repeat("я", MaxTitleLength+1), Content: "Content"},
+			wantErr: ErrTitleTooLong},
+		{name: "empty content", note: Note{Title: "Title"}, wantErr: ErrEmptyContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.note.Validate(), tt.wantErr)
+		})
+	}
+}
+
 func TestNote_CreateNote(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -27,20 +48,76 @@ func TestNote_CreateNote(t *testing.T) {
 		UserID:  1,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
-		AddRow(1, time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at", "version"}).
+		AddRow(1, time.Now(), time.Now(), 1)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notes (title, content, user_id) 
-VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`)).
-		WithArgs(note.Title, note.Content, note.UserID).
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM notebooks WHERE user_id=$1 AND is_default`)).
+		WithArgs(note.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notes (title, content, user_id, notebook_id, pinned, archived) 
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, version`)).
+		WithArgs(note.Title, note.Content, note.UserID, 3, false, false).
 		WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_revisions (note_id, author_id, title, content, created_at)`)).
+		WithArgs(1, note.UserID, note.Title, note.Content, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = note.CreateNote(sqlxDB)
 	assert.NoError(t, err)
+	assert.Equal(t, 3, note.NotebookID)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestNote_CreateNote_WithTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	note := &Note{
+		Title:      "Test Title",
+		Content:    "Test Content",
+		UserID:     1,
+		NotebookID: 3,
+		Tags:       []string{"work"},
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at", "version"}).
+		AddRow(5, time.Now(), time.Now(), 1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notes (title, content, user_id, notebook_id, pinned, archived)`)).
+		WithArgs(note.Title, note.Content, note.UserID, 3, false, false).
+		WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_revisions (note_id, author_id, title, content, created_at)`)).
+		WithArgs(5, note.UserID, note.Title, note.Content, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM note_tags WHERE note_id=$1`)).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO tags (user_id, name) VALUES ($1, $2)`)).
+		WithArgs(1, "work").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_tags (note_id, tag_id) VALUES ($1, $2)`)).
+		WithArgs(5, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM tags WHERE user_id=$1 AND NOT EXISTS`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = note.CreateNote(sqlxDB)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, note.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestNote_UpdateNote(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -51,19 +128,108 @@ func TestNote_UpdateNote(t *testing.T) {
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 
 	note := &Note{
-		ID:        1,
-		Title:     "Updated Title",
-		Content:   "Updated Content",
-		UpdatedAt: time.Now(),
+		ID:         1,
+		Title:      "Updated Title",
+		Content:    "Updated Content",
+		UserID:     1,
+		NotebookID: 7,
+		Pinned:     true,
+		UpdatedAt:  time.Now(),
+		Version:    3,
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET title=?, content=?, updated_at=?
-             WHERE id=?`)).
-		WithArgs(note.Title, note.Content, sqlmock.AnyArg(), note.ID).
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET title=$1, content=$2, notebook_id=$3, pinned=$4, archived=$5,
+updated_at=$6, version=version+1 WHERE id=$7 AND version=$8`)).
+		WithArgs(note.Title, note.Content, 7, true, false, sqlmock.AnyArg(), note.ID, 3).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_revisions (note_id, author_id, title, content, created_at)`)).
+		WithArgs(note.ID, 2, note.Title, note.Content, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	err = note.UpdateNote(sqlxDB)
+	err = note.UpdateNote(sqlxDB, 2, nil)
 	assert.NoError(t, err)
+	assert.Equal(t, 4, note.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNote_UpdateNote_WithTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	note := &Note{ID: 5, Title: "Title", Content: "Content", UserID: 1, NotebookID: 7, Version: 1}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_revisions`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM note_tags WHERE note_id=$1`)).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO tags (user_id, name) VALUES ($1, $2)`)).
+		WithArgs(1, "work").
+		WillReturnError(errors.New("insert failed"))
+	mock.ExpectRollback()
+
+	// Ошибка при сохранении тегов откатывает и изменение текста.
+	err = note.UpdateNote(sqlxDB, 2, []string{"work"})
+	assert.Error(t, err)
+	assert.Equal(t, 1, note.Version)
+	assert.Nil(t, note.Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNote_UpdateNote_VersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	note := &Note{ID: 1, Title: "Stale Title", Content: "Stale Content", Version: 2}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET`)).
+		WithArgs(note.Title, note.Content, 0, false, false, sqlmock.AnyArg(), note.ID, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = note.UpdateNote(sqlxDB, 1, nil)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Equal(t, 2, note.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNote_UpdateNote_RollbackOnRevisionError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	note := &Note{ID: 1, Title: "Updated Title", Content: "Updated Content"}
+	expectedError := errors.New("insert failed")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_revisions`)).
+		WillReturnError(expectedError)
+	mock.ExpectRollback()
+
+	err = note.UpdateNote(sqlxDB, 1, nil)
+	assert.ErrorIs(t, err, expectedError)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -80,11 +246,13 @@ func TestNote_DeleteNote(t *testing.T) {
 		ID: 1,
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE id=?`)).WithArgs(note.ID).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET deleted_at=? WHERE id=?`)).
+		WithArgs(sqlmock.AnyArg(), note.ID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = note.DeleteNote(sqlxDB)
 	assert.NoError(t, err)
+	assert.NotNil(t, note.DeletedAt)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -99,27 +267,57 @@ func TestGetNotesByUser(t *testing.T) {
 
 	userID := 1
 	expectedNotes := []Note{
-		{ID: 1, Title: "Note 1", Content: "Content 1", CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		{ID: 2, Title: "Note 2", Content: "Content 2", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 1, Title: "Note 1", Content: "Content 1", UserID: userID, NotebookID: 1, CreatedAt: time.Now(),
+			UpdatedAt: time.Now()},
+		{ID: 2, Title: "Note 2", Content: "Content 2", UserID: userID, NotebookID: 1, CreatedAt: time.Now(),
+			UpdatedAt: time.Now()},
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "title", "content", "created_at", "updated_at"}).
-		AddRow(expectedNotes[0].ID, expectedNotes[0].Title, expectedNotes[0].Content, expectedNotes[0].CreatedAt,
-			expectedNotes[0].UpdatedAt).
-		AddRow(expectedNotes[1].ID, expectedNotes[1].Title, expectedNotes[1].Content, expectedNotes[1].CreatedAt,
-			expectedNotes[1].UpdatedAt)
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "notebook_id", "created_at", "updated_at"}).
+		AddRow(expectedNotes[0].ID, expectedNotes[0].Title, expectedNotes[0].Content, expectedNotes[0].UserID,
+			expectedNotes[0].NotebookID, expectedNotes[0].CreatedAt, expectedNotes[0].UpdatedAt).
+		AddRow(expectedNotes[1].ID, expectedNotes[1].Title, expectedNotes[1].Content, expectedNotes[1].UserID,
+			expectedNotes[1].NotebookID, expectedNotes[1].CreatedAt, expectedNotes[1].UpdatedAt)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, created_at, updated_at 
-FROM notes WHERE user_id=$1`)).
-		WithArgs(userID).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, notebook_id, pinned, archived, version,
+created_at, updated_at FROM notes WHERE user_id=$1 AND deleted_at IS NULL AND archived=$2
+ORDER BY pinned DESC, updated_at DESC, id DESC LIMIT $3`)).
+		WithArgs(userID, false, DefaultPageSize+1).
 		WillReturnRows(rows)
 
 	note := &Note{}
 
-	notes, err := note.GetNotesByUser(sqlxDB, userID)
+	page, err := note.GetNotesByUser(sqlxDB, userID, NoteFilter{}, PageRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedNotes, page.Notes)
+	assert.Empty(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotesByUser_Filters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
 
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "notebook_id", "created_at", "updated_at"}).
+		AddRow(1, "Note 1", "Content 1", 1, 2, time.Now(), time.Now())
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM notes WHERE user_id=$1 AND deleted_at IS NULL AND archived=$2
+AND EXISTS (SELECT 1 FROM note_tags nt JOIN tags t ON t.id=nt.tag_id
+WHERE nt.note_id=notes.id AND t.name=$3) AND notebook_id=$4 ORDER BY pinned DESC`)).
+		WithArgs(1, true, "work", 2, DefaultPageSize+1).
+		WillReturnRows(rows)
+
+	note := &Note{}
+
+	page, err := note.GetNotesByUser(sqlxDB, 1, NoteFilter{Tag: "work", NotebookID: 2, Archived: true}, PageRequest{})
 	assert.NoError(t, err)
-	assert.Equal(t, expectedNotes, notes)
+	assert.Len(t, page.Notes, 1)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -142,12 +340,12 @@ func TestGetNoteByID(t *testing.T) {
 		UpdatedAt: time.Now(),
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "created_at", "updated_at"}).
-		AddRow(expectedNote.ID, expectedNote.Title, expectedNote.Content, expectedNote.UserID,
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "notebook_id", "created_at", "updated_at"}).
+		AddRow(expectedNote.ID, expectedNote.Title, expectedNote.Content, expectedNote.UserID, 1,
 			time.Now(), time.Now())
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, created_at, updated_at 
-FROM notes WHERE id=$1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, notebook_id, pinned, archived, version,
+created_at, updated_at FROM notes WHERE id=$1 AND deleted_at IS NULL`)).
 		WithArgs(noteID).
 		WillReturnRows(rows)
 
@@ -173,8 +371,8 @@ func TestGetNoteByID_Error(t *testing.T) {
 
 	noteID := 1
 	expectedError := errors.New("database connection error")
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, created_at, updated_at 
-FROM notes WHERE id=$1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, notebook_id, pinned, archived, version,
+created_at, updated_at FROM notes WHERE id=$1 AND deleted_at IS NULL`)).
 		WithArgs(noteID).
 		WillReturnError(expectedError)
 
@@ -196,8 +394,8 @@ func TestGetNoteByID_NotFound(t *testing.T) {
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 
 	userID := 1
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, created_at, updated_at 
-FROM notes WHERE id=$1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, content, user_id, notebook_id, pinned, archived, version,
+created_at, updated_at FROM notes WHERE id=$1 AND deleted_at IS NULL`)).
 		WithArgs(userID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -207,3 +405,30 @@ FROM notes WHERE id=$1`)).
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestNote_SetPinnedAndArchived(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	note := &Note{ID: 1}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE notes SET pinned=$1, version=version+1 WHERE id=$2 RETURNING version`)).
+		WithArgs(true, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE notes SET archived=$1, version=version+1 WHERE id=$2 RETURNING version`)).
+		WithArgs(true, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	assert.NoError(t, note.SetPinned(sqlxDB, true))
+	assert.Equal(t, 2, note.Version)
+	assert.NoError(t, note.SetArchived(sqlxDB, true))
+	assert.Equal(t, 3, note.Version)
+	assert.True(t, note.Pinned)
+	assert.True(t, note.Archived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	DefaultNotebookName   = "Notes"
+	MaxNotebookNameLength = 100
+)
+
+var (
+	ErrEmptyNotebookName     = errors.New("notebook name is required")
+	ErrNotebookNameTooLong   = errors.New("notebook name is too long")
+	ErrDefaultNotebookDelete = errors.New("the default notebook cannot be deleted")
+	ErrDuplicateNotebookName = errors.New("a notebook with this name already exists")
+)
+
+type Notebook struct {
+	ID        int       `db:"id" json:"id"`
+	UserID    int       `db:"user_id" json:"userId"`
+	Name      string    `db:"name" json:"name"`
+	IsDefault bool      `db:"is_default" json:"isDefault"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	NoteCount int       `db:"note_count" json:"noteCount"`
+}
+
+func ValidateNotebookName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return ErrEmptyNotebookName
+	}
+	if utf8.RuneCountInString(name) > MaxNotebookNameLength {
+		return ErrNotebookNameTooLong
+	}
+	return nil
+}
+
+// EnsureDefaultNotebook возвращает идентификатор блокнота по умолчанию, создавая его при необходимости.
+func EnsureDefaultNotebook(db sqlx.Queryer, userID int) (int, error) {
+	var id int
+	query := `SELECT id FROM notebooks WHERE user_id=$1 AND is_default`
+	err := sqlx.Get(db, &id, query, userID)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// Имя по умолчанию может быть уже занято обычным блокнотом, тогда берём уникальное.
+	query = `INSERT INTO notebooks (user_id, name, is_default)
+SELECT $1::int, CASE WHEN EXISTS (SELECT 1 FROM notebooks WHERE user_id=$1::int AND name=$2::text)
+THEN $2::text || ' (default)' ELSE $2::text END, TRUE
+ON CONFLICT (user_id) WHERE is_default DO UPDATE SET is_default=TRUE RETURNING id`
+	err = sqlx.Get(db, &id, query, userID, DefaultNotebookName)
+	return id, err
+}
+
+// createDefaultNotebook создаёт блокнот по умолчанию новому пользователю, у которого ещё нет блокнотов.
+func createDefaultNotebook(db sqlx.Execer, userID int) error {
+	_, err := db.Exec(`INSERT INTO notebooks (user_id, name, is_default) VALUES ($1, $2, TRUE)`,
+		userID, DefaultNotebookName)
+	return err
+}
+
+func (nb *Notebook) CreateNotebook(db *sqlx.DB) error {
+	if _, err := EnsureDefaultNotebook(db, nb.UserID); err != nil {
+		return err
+	}
+	query := `INSERT INTO notebooks (user_id, name) VALUES ($1, $2) RETURNING id, created_at`
+	err := db.QueryRowx(query, nb.UserID, nb.Name).Scan(&nb.ID, &nb.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateNotebookName
+	}
+	return err
+}
+
+func (nb *Notebook) RenameNotebook(db *sqlx.DB) error {
+	query := `UPDATE notebooks SET name=$1 WHERE id=$2 AND user_id=$3`
+	_, err := db.Exec(query, nb.Name, nb.ID, nb.UserID)
+	if isUniqueViolation(err) {
+		return ErrDuplicateNotebookName
+	}
+	return err
+}
+
+// DeleteNotebook переносит заметки блокнота (включая удалённые в корзину)
+// в блокнот по умолчанию и удаляет сам блокнот.
+func (nb *Notebook) DeleteNotebook(db *sqlx.DB) error {
+	if nb.IsDefault {
+		return ErrDefaultNotebookDelete
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	defaultID, err := EnsureDefaultNotebook(tx, nb.UserID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE notes SET notebook_id=$1 WHERE notebook_id=$2`, defaultID, nb.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM notebooks WHERE id=$1 AND user_id=$2`, nb.ID, nb.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetNotebooksByUser возвращает блокноты пользователя с количеством заметок (без корзины).
+// Блокнот по умолчанию создаётся при регистрации, поэтому чтение ничего не записывает.
+func GetNotebooksByUser(db *sqlx.DB, userID int) ([]Notebook, error) {
+	var notebooks []Notebook
+	query := `SELECT nb.id, nb.user_id, nb.name, nb.is_default, nb.created_at, COUNT(n.id) AS note_count
+FROM notebooks nb LEFT JOIN notes n ON n.notebook_id=nb.id AND n.deleted_at IS NULL
+WHERE nb.user_id=$1 GROUP BY nb.id ORDER BY nb.is_default DESC, nb.name`
+	err := db.Select(&notebooks, query, userID)
+	return notebooks, err
+}
+
+// GetNotebook возвращает блокнот, если он принадлежит пользователю.
+func GetNotebook(db *sqlx.DB, id, userID int) (*Notebook, error) {
+	var notebook Notebook
+	query := `SELECT id, user_id, name, is_default, created_at FROM notebooks WHERE id=$1 AND user_id=$2`
+
+	err := db.Get(&notebook, query, id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &notebook, nil
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNotebookName(t *testing.T) {
+	assert.NoError(t, ValidateNotebookName("Work"))
+	assert.ErrorIs(t, ValidateNotebookName(" "), ErrEmptyNotebookName)
+	assert.ErrorIs(t, ValidateNotebookName(strings.Repeat("a", MaxNotebookNameLength+1)), ErrNotebookNameTooLong)
+}
+
+func TestEnsureDefaultNotebook_Creates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM notebooks WHERE user_id=$1 AND is_default`)).
+		WithArgs(1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notebooks (user_id, name, is_default)`)).
+		WithArgs(1, DefaultNotebookName).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	id, err := EnsureDefaultNotebook(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotebook_CreateNotebook_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM notebooks WHERE user_id=$1 AND is_default`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notebooks (user_id, name) VALUES ($1, $2) RETURNING id, created_at`)).
+		WithArgs(1, "Work").
+		WillReturnError(&pq.Error{Code: uniqueViolationCode})
+
+	notebook := &Notebook{UserID: 1, Name: "Work"}
+	err = notebook.CreateNotebook(sqlxDB)
+	assert.ErrorIs(t, err, ErrDuplicateNotebookName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotebook_DeleteNotebook_MovesNotes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM notebooks WHERE user_id=$1 AND is_default`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET notebook_id=$1 WHERE notebook_id=$2`)).
+		WithArgs(5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notebooks WHERE id=$1 AND user_id=$2`)).
+		WithArgs(7, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	notebook := &Notebook{ID: 7, UserID: 1, Name: "Work"}
+	assert.NoError(t, notebook.DeleteNotebook(sqlxDB))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotebook_DeleteNotebook_Default(t *testing.T) {
+	notebook := &Notebook{ID: 5, UserID: 1, IsDefault: true}
+	assert.ErrorIs(t, notebook.DeleteNotebook(nil), ErrDefaultNotebookDelete)
+}
+
+func TestGetNotebook_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM notebooks WHERE id=$1 AND user_id=$2`)).
+		WithArgs(7, 2).
+		WillReturnError(sql.ErrNoRows)
+
+	notebook, err := GetNotebook(sqlxDB, 7, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, notebook)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotebooksByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM notebooks nb LEFT JOIN notes n ON n.notebook_id=nb.id AND n.deleted_at IS NULL`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "is_default", "created_at", "note_count"}).
+			AddRow(5, 1, DefaultNotebookName, true, now, 2).
+			AddRow(7, 1, "Work", false, now, 0))
+
+	notebooks, err := GetNotebooksByUser(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Notebook{
+		{ID: 5, UserID: 1, Name: DefaultNotebookName, IsDefault: true, CreatedAt: now, NoteCount: 2},
+		{ID: 7, UserID: 1, Name: "Work", CreatedAt: now},
+	}, notebooks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// NoteSort задаёт порядок списка заметок. Закреплённые заметки всегда идут первыми.
+type NoteSort string
+
+const (
+	SortUpdated NoteSort = "updated" // сначала недавно изменённые
+	SortCreated NoteSort = "created" // сначала недавно созданные
+	SortTitle   NoteSort = "title"   // по заголовку в алфавитном порядке
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrInvalidSort     = errors.New("sort must be one of: updated, created, title")
+	ErrInvalidPageSize = errors.New("page size must be between 1 and 100")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+)
+
+// PageRequest описывает страницу списка: порядок, размер и курсор, полученный с предыдущей страницы.
+type PageRequest struct {
+	Sort   NoteSort
+	Limit  int
+	Cursor string
+}
+
+type NotePage struct {
+	Notes []Note
+	// NextCursor пуст, если это последняя страница.
+	NextCursor string
+}
+
+// ParseNoteSort разбирает параметр сортировки; пустое значение означает SortUpdated.
+func ParseNoteSort(value string) (NoteSort, error) {
+	switch sort := NoteSort(value); sort {
+	case "":
+		return SortUpdated, nil
+	case SortUpdated, SortCreated, SortTitle:
+		return sort, nil
+	}
+	return "", ErrInvalidSort
+}
+
+// column возвращает колонку сортировки и признак убывающего порядка.
+func (s NoteSort) column() (string, bool) {
+	switch s {
+	case SortCreated:
+		return "created_at", true
+	case SortTitle:
+		return "title", false
+	case SortUpdated:
+	}
+	return "updated_at", true
+}
+
+// noteCursor — позиция последней заметки страницы в порядке сортировки.
+type noteCursor struct {
+	Sort   NoteSort `json:"s"`
+	Pinned bool     `json:"p"`
+	Key    string   `json:"k"`
+	ID     int      `json:"i"`
+}
+
+func encodeNoteCursor(sort NoteSort, n *Note) string {
+	c := noteCursor{Sort: sort, Pinned: n.Pinned, ID: n.ID}
+	switch sort {
+	case SortCreated:
+		c.Key = n.CreatedAt.Format(time.RFC3339Nano)
+	case SortTitle:
+		c.Key = n.Title
+	case SortUpdated:
+		c.Key = n.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(c) // структура из строк, чисел и bool сериализуется всегда
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeNoteCursor проверяет курсор и возвращает значение ключа сортировки для запроса.
+func decodeNoteCursor(sort NoteSort, cursor string) (*noteCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	var c noteCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort || c.ID <= 0 {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	if sort == SortTitle {
+		return &c, c.Key, nil
+	}
+	key, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	return &c, key, nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNoteSort(t *testing.T) {
+	sort, err := ParseNoteSort("")
+	assert.NoError(t, err)
+	assert.Equal(t, SortUpdated, sort)
+
+	sort, err = ParseNoteSort("title")
+	assert.NoError(t, err)
+	assert.Equal(t, SortTitle, sort)
+
+	_, err = ParseNoteSort("id; DROP TABLE notes")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestNoteCursor_RoundTrip(t *testing.T) {
+	updated := time.Date(2026, 10, 18, 12, 30, 0, 123456000, time.UTC)
+	note := &Note{ID: 7, Title: "Plans", Pinned: true, UpdatedAt: updated}
+
+	cursor, key, err := decodeNoteCursor(SortUpdated, encodeNoteCursor(SortUpdated, note))
+	assert.NoError(t, err)
+	assert.Equal(t, 7, cursor.ID)
+	assert.True(t, cursor.Pinned)
+	assert.True(t, updated.Equal(key.(time.Time)))
+
+	_, key, err = decodeNoteCursor(SortTitle, encodeNoteCursor(SortTitle, note))
+	assert.NoError(t, err)
+	assert.Equal(t, "Plans", key)
+}
+
+func TestNoteCursor_Invalid(t *testing.T) {
+	note := &Note{ID: 7, Title: "Plans"}
+
+	_, _, err := decodeNoteCursor(SortUpdated, encodeNoteCursor(SortTitle, note))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, _, err = decodeNoteCursor(SortUpdated, "not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestGetNotesByUser_Cursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	cursor := encodeNoteCursor(SortTitle, &Note{ID: 4, Title: "B"})
+
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "notebook_id", "created_at", "updated_at"}).
+		AddRow(5, "C", "c", 1, 1, time.Now(), time.Now()).
+		AddRow(6, "D", "d", 1, 1, time.Now(), time.Now()).
+		AddRow(7, "E", "e", 1, 1, time.Now(), time.Now())
+
+	mock.ExpectQuery(regexp.QuoteMeta(`AND (pinned < $3 OR (pinned = $3 AND (title, id) > ($4, $5)))
+ORDER BY pinned DESC, title ASC, id ASC LIMIT $6`)).
+		WithArgs(1, false, false, "B", 4, 3).
+		WillReturnRows(rows)
+
+	note := &Note{}
+
+	page, err := note.GetNotesByUser(sqlxDB, 1, NoteFilter{}, PageRequest{Sort: SortTitle, Limit: 2, Cursor: cursor})
+	assert.NoError(t, err)
+	assert.Len(t, page.Notes, 2)
+	assert.Equal(t, encodeNoteCursor(SortTitle, &page.Notes[1]), page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotesByUser_InvalidPageSize(t *testing.T) {
+	note := &Note{}
+
+	_, err := note.GetNotesByUser(nil, 1, NoteFilter{}, PageRequest{Limit: MaxPageSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidPageSize)
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	passwordResetTokenBytes = 32
+	// PasswordResetLifetime — срок действия ссылки для сброса пароля.
+	PasswordResetLifetime = time.Hour
+)
+
+var ErrPasswordResetInvalid = errors.New("password reset link is invalid or has expired")
+
+// PasswordReset — одноразовая ссылка для сброса пароля. В базе хранится только хеш токена.
+type PasswordReset struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// GeneratePasswordResetToken возвращает случайный токен для ссылки сброса пароля.
+func GeneratePasswordResetToken() (string, error) {
+	return generateToken(passwordResetTokenBytes)
+}
+
+// CreatePasswordReset сохраняет ссылку сброса, вычисляя хеш из открытого токена.
+// Неиспользованные ссылки, отправленные пользователю раньше, отзываются: действует только последняя.
+func (pr *PasswordReset) CreatePasswordReset(db *sqlx.DB, plain string) error {
+	pr.TokenHash = HashToken(plain)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM password_resets WHERE user_id=$1 AND used_at IS NULL`, pr.UserID); err != nil {
+		return err
+	}
+	query := `INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at`
+	if err := tx.QueryRowx(query, pr.UserID, pr.TokenHash, pr.ExpiresAt).Scan(&pr.ID, &pr.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPasswordResetByToken возвращает неиспользованную и не истёкшую ссылку сброса по открытому токену.
+func GetPasswordResetByToken(db *sqlx.DB, plain string) (*PasswordReset, error) {
+	var reset PasswordReset
+	query := `SELECT id, user_id, token_hash, created_at, expires_at, used_at FROM password_resets
+WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2`
+
+	err := db.Get(&reset, query, HashToken(plain), time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// ResetPassword устанавливает новый хеш пароля по ссылке сброса. Ссылка помечается использованной,
+// остальные ссылки пользователя отзываются, все его сессии завершаются, а токены API отзываются.
+// Если ссылку успели использовать или она истекла, возвращает ErrPasswordResetInvalid.
+func (pr *PasswordReset) ResetPassword(db *sqlx.DB, passwordHash string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	res, err := tx.Exec(`UPDATE password_resets SET used_at=$1 WHERE id=$2 AND used_at IS NULL AND expires_at > $1`,
+		now, pr.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrPasswordResetInvalid
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET password=$1 WHERE id=$2`, passwordHash, pr.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM password_resets WHERE user_id=$1 AND id<>$2`, pr.UserID, pr.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id=$1`, pr.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM api_tokens WHERE user_id=$1`, pr.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	pr.UsedAt = &now
+	return nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordReset_CreatePasswordReset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	expires := time.Now().Add(PasswordResetLifetime)
+	reset := &PasswordReset{UserID: 1, ExpiresAt: expires}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM password_resets WHERE user_id=$1 AND used_at IS NULL`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+RETURNING id, created_at`)).
+		WithArgs(1, HashToken("plain"), expires).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, time.Now()))
+	mock.ExpectCommit()
+
+	err = reset.CreatePasswordReset(sqlxDB, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, reset.ID)
+	assert.Equal(t, HashToken("plain"), reset.TokenHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPasswordResetByToken_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id, token_hash, created_at, expires_at, used_at FROM password_resets
+WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2`)).
+		WithArgs(HashToken("used"), sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+
+	reset, err := GetPasswordResetByToken(sqlxDB, "used")
+	assert.NoError(t, err)
+	assert.Nil(t, reset)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPasswordReset_ResetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	reset := &PasswordReset{ID: 4, UserID: 1}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE password_resets SET used_at=$1 WHERE id=$2 AND used_at IS NULL AND expires_at > $1`)).
+		WithArgs(sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET password=$1 WHERE id=$2`)).
+		WithArgs("new-hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM password_resets WHERE user_id=$1 AND id<>$2`)).
+		WithArgs(1, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM api_tokens WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = reset.ResetPassword(sqlxDB, "new-hash")
+	assert.NoError(t, err)
+	assert.NotNil(t, reset.UsedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPasswordReset_ResetPassword_AlreadyUsed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	reset := &PasswordReset{ID: 4, UserID: 1}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE password_resets SET used_at=$1`)).
+		WithArgs(sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = reset.ResetPassword(sqlxDB, "new-hash")
+	assert.True(t, errors.Is(err, ErrPasswordResetInvalid))
+	assert.Nil(t, reset.UsedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	publicLinkTokenBytes   = 24
+	publicLinkDisplayChars = 8
+)
+
+type PublicLink struct {
+	ID           int        `db:"id"`
+	NoteID       int        `db:"note_id"`
+	TokenPrefix  string     `db:"token_prefix"`
+	TokenHash    string     `db:"token_hash"`
+	PasswordHash *string    `db:"password_hash"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	ViewCount    int        `db:"view_count"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// GeneratePublicLinkToken создаёт случайный токен для публичной ссылки.
+func GeneratePublicLinkToken() (string, error) {
+	return generateToken(publicLinkTokenBytes)
+}
+
+func (pl *PublicLink) HasPassword() bool {
+	return pl.PasswordHash != nil
+}
+
+func (pl *PublicLink) CheckPassword(password string) bool {
+	if pl.PasswordHash == nil {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(*pl.PasswordHash), []byte(password)) == nil
+}
+
+func (pl *PublicLink) IsExpired(now time.Time) bool {
+	return pl.ExpiresAt != nil && !now.Before(*pl.ExpiresAt)
+}
+
+// SetPassword защищает ссылку паролем; пустой пароль снимает защиту.
+func (pl *PublicLink) SetPassword(password string) error {
+	if password == "" {
+		pl.PasswordHash = nil
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	hashed := string(hash)
+	pl.PasswordHash = &hashed
+	return nil
+}
+
+func (pl *PublicLink) CreatePublicLink(db *sqlx.DB, plain string) error {
+	pl.TokenHash = HashToken(plain)
+	pl.TokenPrefix = plain[:publicLinkDisplayChars]
+
+	query := `INSERT INTO public_links (note_id, token_prefix, token_hash, password_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return db.QueryRowx(query, pl.NoteID, pl.TokenPrefix, pl.TokenHash, pl.PasswordHash, pl.ExpiresAt).
+		Scan(&pl.ID, &pl.CreatedAt)
+}
+
+func GetPublicLinksByNote(db *sqlx.DB, noteID int) ([]PublicLink, error) {
+	var links []PublicLink
+	query := `SELECT id, note_id, token_prefix, token_hash, password_hash, expires_at, view_count, created_at
+FROM public_links WHERE note_id=$1 ORDER BY created_at DESC`
+	err := db.Select(&links, query, noteID)
+	return links, err
+}
+
+func GetPublicLinkByToken(db *sqlx.DB, plain string) (*PublicLink, error) {
+	var link PublicLink
+	query := `SELECT id, note_id, token_prefix, token_hash, password_hash, expires_at, view_count, created_at
+FROM public_links WHERE token_hash=$1`
+
+	err := db.Get(&link, query, HashToken(plain))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (pl *PublicLink) IncrementViews(db *sqlx.DB) error {
+	query := `UPDATE public_links SET view_count=view_count+1 WHERE id=$1 RETURNING view_count`
+	return db.QueryRowx(query, pl.ID).Scan(&pl.ViewCount)
+}
+
+// DeletePublicLink отзывает ссылку. Возвращает false, если у заметки нет такой ссылки.
+func DeletePublicLink(db *sqlx.DB, id, noteID int) (bool, error) {
+	query := `DELETE FROM public_links WHERE id=$1 AND note_id=$2`
+	res, err := db.Exec(query, id, noteID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicLink_Password(t *testing.T) {
+	link := &PublicLink{}
+	assert.False(t, link.HasPassword())
+	assert.True(t, link.CheckPassword("anything"))
+
+	assert.NoError(t, link.SetPassword("secret"))
+	assert.True(t, link.HasPassword())
+	assert.True(t, link.CheckPassword("secret"))
+	assert.False(t, link.CheckPassword("wrong"))
+
+	assert.NoError(t, link.SetPassword(""))
+	assert.False(t, link.HasPassword())
+}
+
+func TestPublicLink_CreatePublicLink(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	plain, err := GeneratePublicLinkToken()
+	assert.NoError(t, err)
+
+	link := &PublicLink{NoteID: 1}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO public_links (note_id, token_prefix, token_hash, password_hash, expires_at)`)).
+		WithArgs(1, plain[:publicLinkDisplayChars], HashToken(plain), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, time.Now()))
+
+	err = link.CreatePublicLink(sqlxDB, plain)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, link.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPublicLinkByToken_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM public_links WHERE token_hash=$1`)).
+		WithArgs(HashToken("missing")).
+		WillReturnError(sql.ErrNoRows)
+
+	link, err := GetPublicLinkByToken(sqlxDB, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, link)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublicLink_IncrementViews(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	link := &PublicLink{ID: 4, ViewCount: 1}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE public_links SET view_count=view_count+1 WHERE id=$1 RETURNING view_count`)).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"view_count"}).AddRow(2))
+
+	err = link.IncrementViews(sqlxDB)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, link.ViewCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type NoteRevision struct {
+	ID          int       `db:"id" json:"id"`
+	NoteID      int       `db:"note_id" json:"noteId"`
+	AuthorID    *int      `db:"author_id" json:"authorId"`
+	AuthorEmail *string   `db:"author_email" json:"authorEmail"`
+	Title       string    `db:"title" json:"title"`
+	Content     string    `db:"content" json:"content"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+}
+
+// createRevision сохраняет текущее состояние заметки как новую ревизию.
+func createRevision(tx *sqlx.Tx, n *Note, authorID int) error {
+	query := `INSERT INTO note_revisions (note_id, author_id, title, content, created_at)
+VALUES ($1, $2, $3, $4, $5)`
+	_, err := tx.Exec(query, n.ID, authorID, n.Title, n.Content, n.UpdatedAt)
+	return err
+}
+
+// GetNoteRevisions возвращает ревизии заметки, начиная с самой новой.
+func GetNoteRevisions(db *sqlx.DB, noteID int) ([]NoteRevision, error) {
+	var revisions []NoteRevision
+	query := `SELECT r.id, r.note_id, r.author_id, u.email AS author_email, r.title, r.content, r.created_at
+FROM note_revisions r LEFT JOIN users u ON u.id=r.author_id
+WHERE r.note_id=$1 ORDER BY r.created_at DESC, r.id DESC`
+	err := db.Select(&revisions, query, noteID)
+	return revisions, err
+}
+
+func GetNoteRevision(db *sqlx.DB, noteID, revisionID int) (*NoteRevision, error) {
+	var revision NoteRevision
+	query := `SELECT r.id, r.note_id, r.author_id, u.email AS author_email, r.title, r.content, r.created_at
+FROM note_revisions r LEFT JOIN users u ON u.id=r.author_id
+WHERE r.note_id=$1 AND r.id=$2`
+
+	err := db.Get(&revision, query, noteID, revisionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNoteRevisions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "note_id", "author_id", "author_email", "title", "content", "created_at"}).
+		AddRow(2, 1, 1, "test@example.com", "Title v2", "Content v2", time.Now()).
+		AddRow(1, 1, nil, nil, "Title v1", "Content v1", time.Now())
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM note_revisions r LEFT JOIN users u ON u.id=r.author_id
+WHERE r.note_id=$1 ORDER BY r.created_at DESC, r.id DESC`)).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	revisions, err := GetNoteRevisions(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "test@example.com", *revisions[0].AuthorEmail)
+	assert.Nil(t, revisions[1].AuthorID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNoteRevision_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE r.note_id=$1 AND r.id=$2`)).
+		WithArgs(1, 5).
+		WillReturnError(sql.ErrNoRows)
+
+	revision, err := GetNoteRevision(sqlxDB, 1, 5)
+	assert.NoError(t, err)
+	assert.Nil(t, revision)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
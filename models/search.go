@@ -0,0 +1,59 @@
+package models
+
+import (
+	"html"
+	"html/template"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const MaxSearchResults = 50
+
+// Маркеры подсветки из Private Use Area: ts_headline возвращает сырой текст заметки,
+// поэтому сначала экранируем его, а затем заменяем маркеры на теги <mark>.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop
+)
+
+type SearchResult struct {
+	Note
+	Rank          float64 `db:"rank" json:"rank"`
+	TitleHeadline string  `db:"title_headline" json:"-"`
+	Snippet       string  `db:"snippet" json:"-"`
+}
+
+// HighlightedTitle возвращает заголовок с подсвеченными совпадениями.
+func (sr *SearchResult) HighlightedTitle() template.HTML {
+	return highlight(sr.TitleHeadline)
+}
+
+// HighlightedSnippet возвращает фрагмент содержимого с подсвеченными совпадениями.
+func (sr *SearchResult) HighlightedSnippet() template.HTML {
+	return highlight(sr.Snippet)
+}
+
+func highlight(text string) template.HTML {
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, highlightStop, "</mark>")
+	return template.HTML(escaped) //nolint:gosec // текст экранирован выше
+}
+
+// SearchNotes ищет заметки пользователя по заголовку и содержимому
+// и сортирует их по релевантности.
+func SearchNotes(db *sqlx.DB, userID int, query string) ([]SearchResult, error) {
+	var results []SearchResult
+	sqlQuery := `SELECT id, title, content, user_id, created_at, updated_at,
+ts_rank(search_vector, q) AS rank,
+ts_headline('simple', title, q, $3 || ', HighlightAll=true') AS title_headline,
+ts_headline('simple', content, q, $3 || ', MaxFragments=2, MaxWords=30, MinWords=10') AS snippet
+FROM notes, websearch_to_tsquery('simple', $2) q
+WHERE user_id=$1 AND deleted_at IS NULL AND search_vector @@ q
+ORDER BY rank DESC, updated_at DESC LIMIT $4`
+	err := db.Select(&results, sqlQuery, userID, query, headlineOptions, MaxSearchResults)
+	return results, err
+}
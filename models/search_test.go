@@ -0,0 +1,44 @@
+package models
+
+import (
+	"html/template"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	text := "<script>" + highlightStart + "alert" + highlightStop + "</script>"
+	assert.Equal(t, template.HTML("&lt;script&gt;<mark>alert</mark>&lt;/script&gt;"), highlight(text))
+}
+
+func TestSearchNotes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "created_at", "updated_at",
+		"rank", "title_headline", "snippet"}).
+		AddRow(1, "Go notes", "Learning go", 1, time.Now(), time.Now(), 0.6,
+			highlightStart+"Go"+highlightStop+" notes", "Learning "+highlightStart+"go"+highlightStop)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM notes, websearch_to_tsquery('simple', $2) q
+WHERE user_id=$1 AND deleted_at IS NULL AND search_vector @@ q`)).
+		WithArgs(1, "go", headlineOptions, MaxSearchResults).
+		WillReturnRows(rows)
+
+	results, err := SearchNotes(sqlxDB, 1, "go")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Go notes", results[0].Title)
+	assert.Equal(t, template.HTML("Learning <mark>go</mark>"), results[0].HighlightedSnippet())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
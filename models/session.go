@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const sessionTokenBytes = 32
+
+// Session — серверная сессия браузера. В cookie хранится только случайный токен,
+// в базе — его хеш и сериализованные значения сессии.
+type Session struct {
+	ID         int       `db:"id"`
+	TokenHash  string    `db:"token_hash"`
+	UserID     *int      `db:"user_id"`
+	Data       []byte    `db:"data"`
+	UserAgent  string    `db:"user_agent"`
+	IP         string    `db:"ip"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+}
+
+// GenerateSessionToken возвращает случайный идентификатор новой сессии.
+func GenerateSessionToken() (string, error) {
+	return generateToken(sessionTokenBytes)
+}
+
+// CreateSession сохраняет новую сессию, вычисляя хеш из открытого токена.
+func (s *Session) CreateSession(db *sqlx.DB, plain string) error {
+	s.TokenHash = HashToken(plain)
+
+	query := `INSERT INTO sessions (token_hash, user_id, data, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, last_seen_at`
+	return db.QueryRowx(query, s.TokenHash, s.UserID, s.Data, s.UserAgent, s.IP, s.ExpiresAt).
+		Scan(&s.ID, &s.CreatedAt, &s.LastSeenAt)
+}
+
+// GetSessionByToken возвращает действующую сессию по открытому токену из cookie.
+func GetSessionByToken(db *sqlx.DB, plain string) (*Session, error) {
+	var session Session
+	query := `SELECT id, token_hash, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at
+FROM sessions WHERE token_hash=$1 AND expires_at > $2`
+
+	err := db.Get(&session, query, HashToken(plain), time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionsByUser возвращает действующие сессии пользователя, начиная с последней активной.
+func GetSessionsByUser(db *sqlx.DB, userID int) ([]Session, error) {
+	var sessions []Session
+	query := `SELECT id, token_hash, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at
+FROM sessions WHERE user_id=$1 AND expires_at > $2 ORDER BY last_seen_at DESC`
+	err := db.Select(&sessions, query, userID, time.Now())
+	return sessions, err
+}
+
+// UpdateSession сохраняет значения сессии и продлевает её до ExpiresAt.
+// Завершённая тем временем сессия не восстанавливается.
+func (s *Session) UpdateSession(db *sqlx.DB, plain string) error {
+	s.TokenHash = HashToken(plain)
+
+	query := `UPDATE sessions SET user_id=$1, data=$2, expires_at=$3 WHERE token_hash=$4`
+	_, err := db.Exec(query, s.UserID, s.Data, s.ExpiresAt, s.TokenHash)
+	return err
+}
+
+// TouchSession отмечает активность сессии и запоминает адрес, с которого она пришла.
+func (s *Session) TouchSession(db *sqlx.DB, ip string) error {
+	now := time.Now()
+	query := `UPDATE sessions SET last_seen_at=$1, ip=$2 WHERE id=$3`
+	if _, err := db.Exec(query, now, ip, s.ID); err != nil {
+		return err
+	}
+	s.LastSeenAt = now
+	s.IP = ip
+	return nil
+}
+
+// DeleteSessionByToken удаляет сессию по открытому токену, например при выходе.
+func DeleteSessionByToken(db *sqlx.DB, plain string) error {
+	query := `DELETE FROM sessions WHERE token_hash=$1`
+	_, err := db.Exec(query, HashToken(plain))
+	return err
+}
+
+// DeleteSession завершает сессию пользователя. Возвращает false, если сессия не найдена у пользователя.
+func DeleteSession(db *sqlx.DB, id, userID int) (bool, error) {
+	query := `DELETE FROM sessions WHERE id=$1 AND user_id=$2`
+	res, err := db.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// DeleteUserSessions завершает все сессии пользователя на всех устройствах.
+func DeleteUserSessions(db *sqlx.DB, userID int) (int64, error) {
+	query := `DELETE FROM sessions WHERE user_id=$1`
+	res, err := db.Exec(query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpiredSessions удаляет сессии, срок действия которых истёк до момента before.
+func PurgeExpiredSessions(db *sqlx.DB, before time.Time) (int64, error) {
+	query := `DELETE FROM sessions WHERE expires_at <= $1`
+	res, err := db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
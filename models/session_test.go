@@ -0,0 +1,197 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSessionToken(t *testing.T) {
+	first, err := GenerateSessionToken()
+	assert.NoError(t, err)
+	second, err := GenerateSessionToken()
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestSession_CreateSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	userID := 1
+	expires := time.Now().Add(time.Hour)
+	session := &Session{UserID: &userID, Data: []byte("data"), UserAgent: "curl/8.0", IP: "10.0.0.1", ExpiresAt: expires}
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO sessions (token_hash, user_id, data, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, last_seen_at`)).
+		WithArgs(HashToken("plain"), &userID, []byte("data"), "curl/8.0", "10.0.0.1", expires).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "last_seen_at"}).AddRow(5, now, now))
+
+	err = session.CreateSession(sqlxDB, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, session.ID)
+	assert.Equal(t, HashToken("plain"), session.TokenHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSessionByToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "token_hash", "user_id", "data", "user_agent", "ip", "created_at", "last_seen_at", "expires_at",
+	}).AddRow(5, HashToken("plain"), 1, []byte("data"), "curl/8.0", "10.0.0.1", now, now, now.Add(time.Hour))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, token_hash, user_id, data, user_agent, ip, created_at, last_seen_at,
+expires_at FROM sessions WHERE token_hash=$1 AND expires_at > $2`)).
+		WithArgs(HashToken("plain"), sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	session, err := GetSessionByToken(sqlxDB, "plain")
+	assert.NoError(t, err)
+	if assert.NotNil(t, session) {
+		assert.Equal(t, 5, session.ID)
+		assert.Equal(t, 1, *session.UserID)
+		assert.Equal(t, []byte("data"), session.Data)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSessionByToken_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM sessions WHERE token_hash=$1 AND expires_at > $2`)).
+		WithArgs(HashToken("revoked"), sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+
+	session, err := GetSessionByToken(sqlxDB, "revoked")
+	assert.NoError(t, err)
+	assert.Nil(t, session)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSession_UpdateSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	expires := time.Now().Add(time.Hour)
+	session := &Session{Data: []byte("data"), ExpiresAt: expires}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE sessions SET user_id=$1, data=$2, expires_at=$3 WHERE token_hash=$4`)).
+		WithArgs(nil, []byte("data"), expires, HashToken("plain")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = session.UpdateSession(sqlxDB, "plain")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSession_TouchSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	session := &Session{ID: 5, IP: "10.0.0.1"}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE sessions SET last_seen_at=$1, ip=$2 WHERE id=$3`)).
+		WithArgs(sqlmock.AnyArg(), "10.0.0.2", 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = session.TouchSession(sqlxDB, "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", session.IP)
+	assert.False(t, session.LastSeenAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE id=$1 AND user_id=$2`)).
+		WithArgs(5, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	deleted, err := DeleteSession(sqlxDB, 5, 2)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUserSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	revoked, err := DeleteUserSessions(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), revoked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeExpiredSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE expires_at <= $1`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	purged, err := PurgeExpiredSessions(sqlxDB, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	PermissionViewer = "viewer"
+	PermissionEditor = "editor"
+)
+
+var ErrInvalidPermission = errors.New("permission must be viewer or editor")
+
+// Access — уровень доступа пользователя к заметке.
+type Access int
+
+const (
+	AccessNone Access = iota
+	AccessView
+	AccessEdit
+	AccessOwner
+)
+
+type NoteShare struct {
+	NoteID     int       `db:"note_id" json:"noteId"`
+	UserID     int       `db:"user_id" json:"userId"`
+	Email      string    `db:"email" json:"email"`
+	Permission string    `db:"permission" json:"permission"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// SharedNote — заметка другого пользователя, к которой открыт доступ.
+type SharedNote struct {
+	Note
+	OwnerEmail string `db:"owner_email" json:"ownerEmail"`
+	Permission string `db:"permission" json:"permission"`
+}
+
+func (sn *SharedNote) CanEdit() bool {
+	return sn.Permission == PermissionEditor
+}
+
+func ValidatePermission(permission string) error {
+	if permission != PermissionViewer && permission != PermissionEditor {
+		return ErrInvalidPermission
+	}
+	return nil
+}
+
+// NoteAccess определяет, что пользователь может делать с заметкой.
+func NoteAccess(db *sqlx.DB, note *Note, userID int) (Access, error) {
+	if note.UserID == userID {
+		return AccessOwner, nil
+	}
+
+	var permission string
+	query := `SELECT permission FROM note_shares WHERE note_id=$1 AND user_id=$2`
+	err := db.Get(&permission, query, note.ID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AccessNone, nil
+		}
+		return AccessNone, err
+	}
+
+	if permission == PermissionEditor {
+		return AccessEdit, nil
+	}
+	return AccessView, nil
+}
+
+// ShareNote выдаёт пользователю доступ к заметке или меняет уже выданный.
+func ShareNote(db *sqlx.DB, noteID, userID int, permission string) error {
+	if err := ValidatePermission(permission); err != nil {
+		return err
+	}
+	query := `INSERT INTO note_shares (note_id, user_id, permission) VALUES ($1, $2, $3)
+ON CONFLICT (note_id, user_id) DO UPDATE SET permission=EXCLUDED.permission`
+	_, err := db.Exec(query, noteID, userID, permission)
+	return err
+}
+
+func UnshareNote(db *sqlx.DB, noteID, userID int) error {
+	query := `DELETE FROM note_shares WHERE note_id=$1 AND user_id=$2`
+	_, err := db.Exec(query, noteID, userID)
+	return err
+}
+
+func GetNoteShares(db *sqlx.DB, noteID int) ([]NoteShare, error) {
+	var shares []NoteShare
+	query := `SELECT s.note_id, s.user_id, u.email, s.permission, s.created_at
+FROM note_shares s JOIN users u ON u.id=s.user_id WHERE s.note_id=$1 ORDER BY u.email`
+	err := db.Select(&shares, query, noteID)
+	return shares, err
+}
+
+// GetNotesSharedWithUser возвращает заметки других пользователей, доступные userID.
+func GetNotesSharedWithUser(db *sqlx.DB, userID int) ([]SharedNote, error) {
+	var notes []SharedNote
+	query := `SELECT n.id, n.title, n.content, n.user_id, n.created_at, n.updated_at,
+u.email AS owner_email, s.permission
+FROM note_shares s JOIN notes n ON n.id=s.note_id JOIN users u ON u.id=n.user_id
+WHERE s.user_id=$1 AND n.deleted_at IS NULL ORDER BY n.updated_at DESC`
+	err := db.Select(&notes, query, userID)
+	return notes, err
+}
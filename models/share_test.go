@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoteAccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	note := &Note{ID: 1, UserID: 1}
+	query := regexp.QuoteMeta(`SELECT permission FROM note_shares WHERE note_id=$1 AND user_id=$2`)
+
+	access, err := NoteAccess(sqlxDB, note, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, AccessOwner, access)
+
+	mock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(PermissionEditor))
+	access, err = NoteAccess(sqlxDB, note, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, AccessEdit, access)
+
+	mock.ExpectQuery(query).WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(PermissionViewer))
+	access, err = NoteAccess(sqlxDB, note, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, AccessView, access)
+
+	mock.ExpectQuery(query).WithArgs(1, 4).WillReturnError(sql.ErrNoRows)
+	access, err = NoteAccess(sqlxDB, note, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, AccessNone, access)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShareNote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO note_shares (note_id, user_id, permission) VALUES ($1, $2, $3)`)).
+		WithArgs(1, 2, PermissionViewer).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, ShareNote(sqlxDB, 1, 2, PermissionViewer))
+	assert.ErrorIs(t, ShareNote(sqlxDB, 1, 2, "owner"), ErrInvalidPermission)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotesSharedWithUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "created_at", "updated_at",
+		"owner_email", "permission"}).
+		AddRow(1, "Shared", "Content", 1, time.Now(), time.Now(), "owner@example.com", PermissionEditor)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE s.user_id=$1 AND n.deleted_at IS NULL`)).
+		WithArgs(2).
+		WillReturnRows(rows)
+
+	notes, err := GetNotesSharedWithUser(sqlxDB, 2)
+	assert.NoError(t, err)
+	assert.Len(t, notes, 1)
+	assert.Equal(t, "owner@example.com", notes[0].OwnerEmail)
+	assert.True(t, notes[0].CanEdit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
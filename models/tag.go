@@ -0,0 +1,139 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	MaxTagLength   = 50
+	MaxTagsPerNote = 20
+)
+
+var (
+	ErrTagTooLong  = errors.New("tag is too long")
+	ErrTooManyTags = errors.New("too many tags")
+)
+
+type Tag struct {
+	ID        int    `db:"id" json:"id"`
+	UserID    int    `db:"user_id" json:"-"`
+	Name      string `db:"name" json:"name"`
+	NoteCount int    `db:"note_count" json:"noteCount"`
+}
+
+// ParseTags разбирает строку тегов через запятую: обрезает пробелы,
+// приводит к нижнему регистру и убирает пустые значения и дубликаты.
+func ParseTags(input string) []string {
+	return NormalizeTags(strings.Split(input, ","))
+}
+
+func NormalizeTags(names []string) []string {
+	tags := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// TagsString возвращает теги заметки строкой для поля формы.
+func (n *Note) TagsString() string {
+	return strings.Join(n.Tags, ", ")
+}
+
+func ValidateTags(tags []string) error {
+	if len(tags) > MaxTagsPerNote {
+		return ErrTooManyTags
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return ErrTagTooLong
+		}
+	}
+	return nil
+}
+
+// setNoteTags заменяет теги заметки внутри транзакции tx и удаляет теги пользователя,
+// которые больше ни к чему не привязаны.
+func setNoteTags(tx *sqlx.Tx, noteID, userID int, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM note_tags WHERE note_id=$1`, noteID); err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		var tagID int
+		query := `INSERT INTO tags (user_id, name) VALUES ($1, $2)
+ON CONFLICT (user_id, name) DO UPDATE SET name=EXCLUDED.name RETURNING id`
+		if err := tx.QueryRowx(query, userID, name).Scan(&tagID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO note_tags (note_id, tag_id) VALUES ($1, $2)`, noteID, tagID); err != nil {
+			return err
+		}
+	}
+
+	query := `DELETE FROM tags WHERE user_id=$1 AND NOT EXISTS (SELECT 1 FROM note_tags WHERE tag_id=tags.id)`
+	_, err := tx.Exec(query, userID)
+	return err
+}
+
+func (n *Note) LoadTags(db *sqlx.DB) error {
+	notes := []Note{*n}
+	if err := LoadTagsForNotes(db, notes); err != nil {
+		return err
+	}
+	n.Tags = notes[0].Tags
+	return nil
+}
+
+// LoadTagsForNotes заполняет поле Tags у переданных заметок одним запросом.
+func LoadTagsForNotes(db *sqlx.DB, notes []Note) error {
+	if len(notes) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(notes))
+	byID := make(map[int]*Note, len(notes))
+	for i := range notes {
+		ids[i] = int64(notes[i].ID)
+		notes[i].Tags = []string{}
+		byID[notes[i].ID] = &notes[i]
+	}
+
+	var rows []struct {
+		NoteID int    `db:"note_id"`
+		Name   string `db:"name"`
+	}
+	query := `SELECT nt.note_id, t.name FROM note_tags nt JOIN tags t ON t.id=nt.tag_id
+WHERE nt.note_id = ANY($1) ORDER BY t.name`
+	if err := db.Select(&rows, query, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if note, ok := byID[row.NoteID]; ok {
+			note.Tags = append(note.Tags, row.Name)
+		}
+	}
+	return nil
+}
+
+// GetTagCloud возвращает теги пользователя с количеством заметок для каждого.
+func GetTagCloud(db *sqlx.DB, userID int) ([]Tag, error) {
+	var tags []Tag
+	query := `SELECT t.id, t.user_id, t.name, COUNT(nt.note_id) AS note_count
+FROM tags t JOIN note_tags nt ON nt.tag_id=t.id JOIN notes n ON n.id=nt.note_id
+WHERE t.user_id=$1 AND n.deleted_at IS NULL GROUP BY t.id ORDER BY t.name`
+	err := db.Select(&tags, query, userID)
+	return tags, err
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"work", "go", "ideas"}, ParseTags(" Work, go,,ideas , WORK "))
+	assert.Equal(t, []string{}, ParseTags(""))
+}
+
+func TestValidateTags(t *testing.T) {
+	assert.NoError(t, ValidateTags([]string{"work"}))
+	assert.ErrorIs(t, ValidateTags([]string{strings.Repeat("a", MaxTagLength+1)}), ErrTagTooLong)
+	assert.ErrorIs(t, ValidateTags(make([]string, MaxTagsPerNote+1)), ErrTooManyTags)
+}
+
+func TestLoadTagsForNotes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	notes := []Note{{ID: 1}, {ID: 2}}
+
+	rows := sqlmock.NewRows([]string{"note_id", "name"}).
+		AddRow(1, "go").
+		AddRow(1, "work")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT nt.note_id, t.name FROM note_tags nt JOIN tags t ON t.id=nt.tag_id
+WHERE nt.note_id = ANY($1) ORDER BY t.name`)).
+		WithArgs(pq.Array([]int64{1, 2})).
+		WillReturnRows(rows)
+
+	err = LoadTagsForNotes(sqlxDB, notes)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "work"}, notes[0].Tags)
+	assert.Equal(t, []string{}, notes[1].Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTagCloud(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "note_count"}).
+		AddRow(1, 1, "go", 3).
+		AddRow(2, 1, "work", 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM tags t JOIN note_tags nt ON nt.tag_id=t.id`)).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	tags, err := GetTagCloud(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{ID: 1, UserID: 1, Name: "go", NoteCount: 3}, {ID: 2, UserID: 1, Name: "work", NoteCount: 1}}, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Виды действий, число которых ограничивается правилами LoginThrottle.
+const (
+	// ThrottleLinkPassword — неверный пароль публичной ссылки; ключ — ID ссылки.
+	ThrottleLinkPassword = "link.password"
+	// ThrottleLinkPasswordIP — неверный пароль любой публичной ссылки; ключ — IP-адрес.
+	ThrottleLinkPasswordIP = "link.password.ip"
+	// ThrottlePasswordReset — запрос ссылки сброса пароля; ключ — адрес в нижнем регистре.
+	ThrottlePasswordReset = "password.reset"
+	// ThrottlePasswordResetIP — запрос ссылки сброса пароля для любого адреса; ключ — IP-адрес.
+	ThrottlePasswordResetIP = "password.reset.ip"
+	// ThrottleEmailVerification — повторная отправка ссылки подтверждения; ключ — адрес в нижнем регистре.
+	ThrottleEmailVerification = "email.verification"
+	// ThrottleEmailVerificationIP — повторная отправка ссылки подтверждения на любой адрес; ключ — IP-адрес.
+	ThrottleEmailVerificationIP = "email.verification.ip"
+	// ThrottleTwoFactorDisable — неверный пароль или код при отключении второго фактора; ключ — ID пользователя.
+	ThrottleTwoFactorDisable = "2fa.disable"
+)
+
+// RecordThrottleEvent сохраняет действие вида kind с ключом key.
+func RecordThrottleEvent(db *sqlx.DB, kind, key string) error {
+	_, err := db.Exec(`INSERT INTO throttle_events (kind, key, created_at) VALUES ($1, $2, $3)`, kind, key, time.Now())
+	return err
+}
+
+// CountThrottleEvents возвращает количество действий вида kind с ключом key после since и время последнего.
+func CountThrottleEvents(db *sqlx.DB, kind, key string, since time.Time) (LoginFailures, error) {
+	var events LoginFailures
+	query := `SELECT COUNT(*) AS failures, MAX(created_at) AS last_failure FROM throttle_events
+WHERE kind=$1 AND key=$2 AND created_at > $3`
+	err := db.Get(&events, query, kind, key, since)
+	return events, err
+}
+
+// PurgeThrottleEvents удаляет действия старше before.
+func PurgeThrottleEvents(db *sqlx.DB, before time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM throttle_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
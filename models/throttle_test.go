@@ -0,0 +1,72 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordThrottleEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO throttle_events (kind, key, created_at) VALUES ($1, $2, $3)`)).
+		WithArgs(ThrottleLinkPassword, "7", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = RecordThrottleEvent(sqlxDB, ThrottleLinkPassword, "7")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountThrottleEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	since := time.Now().Add(-time.Hour)
+	last := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) AS failures, MAX(created_at) AS last_failure FROM throttle_events
+WHERE kind=$1 AND key=$2 AND created_at > $3`)).
+		WithArgs(ThrottleLinkPasswordIP, "10.0.0.1", since).
+		WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure"}).AddRow(4, last))
+
+	events, err := CountThrottleEvents(sqlxDB, ThrottleLinkPasswordIP, "10.0.0.1", since)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, events.Count)
+	assert.Equal(t, &last, events.Last)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeThrottleEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	before := time.Now().Add(-7 * 24 * time.Hour)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM throttle_events WHERE created_at < $1`)).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	purged, err := PurgeThrottleEvents(sqlxDB, before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
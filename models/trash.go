@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func GetTrashedNotesByUser(db *sqlx.DB, userID int) ([]Note, error) {
+	var notes []Note
+	query := `SELECT id, title, content, user_id, created_at, updated_at, deleted_at FROM notes
+WHERE user_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+	err := db.Select(&notes, query, userID)
+	return notes, err
+}
+
+func GetTrashedNoteByID(db *sqlx.DB, id int) (*Note, error) {
+	var note Note
+	query := `SELECT id, title, content, user_id, created_at, updated_at, deleted_at FROM notes
+WHERE id=$1 AND deleted_at IS NOT NULL`
+
+	err := db.Get(&note, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (n *Note) RestoreNote(db *sqlx.DB) error {
+	query := `UPDATE notes SET deleted_at=NULL WHERE id=$1`
+	if _, err := db.Exec(query, n.ID); err != nil {
+		return err
+	}
+	n.DeletedAt = nil
+	return nil
+}
+
+// PurgeNote окончательно удаляет заметку вместе с тегами и ревизиями.
+// Содержимое вложений затем удаляет из хранилища задача jobs.PurgeAttachments.
+func (n *Note) PurgeNote(db *sqlx.DB) error {
+	query := `DELETE FROM notes WHERE id=$1`
+	_, err := db.Exec(query, n.ID)
+	return err
+}
+
+func EmptyTrash(db *sqlx.DB, userID int) (int64, error) {
+	query := `DELETE FROM notes WHERE user_id=$1 AND deleted_at IS NOT NULL`
+	res, err := db.Exec(query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeTrash удаляет все заметки, находящиеся в корзине дольше, чем до момента before.
+func PurgeTrash(db *sqlx.DB, before time.Time) (int64, error) {
+	query := `DELETE FROM notes WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	res, err := db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
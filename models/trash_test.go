@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrashedNotesByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	deletedAt := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "user_id", "created_at", "updated_at", "deleted_at"}).
+		AddRow(1, "Note 1", "Content 1", 1, time.Now(), time.Now(), deletedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM notes
+WHERE user_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`)).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	notes, err := GetTrashedNotesByUser(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Len(t, notes, 1)
+	assert.Equal(t, deletedAt, *notes[0].DeletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTrashedNoteByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id=$1 AND deleted_at IS NOT NULL`)).
+		WithArgs(1).
+		WillReturnError(sql.ErrNoRows)
+
+	note, err := GetTrashedNoteByID(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, note)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNote_RestoreNote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	deletedAt := time.Now()
+	note := &Note{ID: 1, DeletedAt: &deletedAt}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notes SET deleted_at=NULL WHERE id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = note.RestoreNote(sqlxDB)
+	assert.NoError(t, err)
+	assert.Nil(t, note.DeletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNote_PurgeNote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = (&Note{ID: 1}).PurgeNote(sqlxDB)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	before := time.Now().AddDate(0, 0, -30)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE deleted_at IS NOT NULL AND deleted_at < $1`)).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	purged, err := PurgeTrash(sqlxDB, before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// RecoveryCodeCount — сколько кодов восстановления выдаётся при включении двухфакторной аутентификации.
+	RecoveryCodeCount = 10
+	recoveryCodeBytes = 7
+	recoveryCodeLen   = 10
+)
+
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes возвращает новый набор одноразовых кодов восстановления вида xxxxx-xxxxx.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	buf := make([]byte, recoveryCodeBytes)
+	for range RecoveryCodeCount {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:recoveryCodeLen]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode приводит код к каноническому виду и возвращает его хеш,
+// чтобы код принимался независимо от регистра, дефисов и пробелов.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return HashToken(code)
+}
+
+// EnableTwoFactor включает двухфакторную аутентификацию с подтверждённым секретом и сохраняет
+// хеши кодов восстановления. step — шаг TOTP, которым пользователь подтвердил настройку.
+func EnableTwoFactor(db *sqlx.DB, userID int, secret string, step int64, codes []string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(`UPDATE users SET totp_secret=$1, totp_enabled_at=$2, totp_last_step=$3
+WHERE id=$4 AND totp_enabled_at IS NULL`, secret, time.Now(), step, userID)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		_, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию и удаляет коды восстановления.
+func DisableTwoFactor(db *sqlx.DB, userID int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret=NULL, totp_enabled_at=NULL, totp_last_step=0
+WHERE id=$1`, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep запоминает шаг принятого кода TOTP. Возвращает false, если код этого или более
+// позднего шага уже использовался, — так перехваченный код нельзя применить повторно.
+func UseTOTPStep(db *sqlx.DB, userID int, step int64) (bool, error) {
+	result, err := db.Exec(`UPDATE users SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// UseRecoveryCode помечает код восстановления использованным. Возвращает false,
+// если такого неиспользованного кода у пользователя нет.
+func UseRecoveryCode(db *sqlx.DB, userID int, code string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at=$1
+WHERE user_id=$2 AND code_hash=$3 AND used_at IS NULL`
+	result, err := db.Exec(query, time.Now(), userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления пользователя.
+func CountRecoveryCodes(db *sqlx.DB, userID int) (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM recovery_codes WHERE user_id=$1 AND used_at IS NULL`, userID)
+	return count, err
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode_Normalizes(t *testing.T) {
+	assert.Equal(t, hashRecoveryCode("abcde-fghij"), hashRecoveryCode("ABCDE FGHIJ"))
+	assert.Equal(t, hashRecoveryCode("abcde-fghij"), hashRecoveryCode("abcdefghij"))
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET totp_secret=$1, totp_enabled_at=$2, totp_last_step=$3
+WHERE id=$4 AND totp_enabled_at IS NULL`)).
+		WithArgs("SECRET", sqlmock.AnyArg(), int64(100), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM recovery_codes WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`)).
+		WithArgs(1, hashRecoveryCode("aaaaa-bbbbb")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`)).
+		WithArgs(1, hashRecoveryCode("ccccc-ddddd")).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err = EnableTwoFactor(sqlxDB, 1, "SECRET", 100, []string{"aaaaa-bbbbb", "ccccc-ddddd"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnableTwoFactor_AlreadyEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET totp_secret=$1`)).
+		WithArgs("SECRET", sqlmock.AnyArg(), int64(100), 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = EnableTwoFactor(sqlxDB, 1, "SECRET", 100, []string{"aaaaa-bbbbb"})
+	assert.True(t, errors.Is(err, ErrTwoFactorEnabled))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET totp_secret=NULL, totp_enabled_at=NULL, totp_last_step=0
+WHERE id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM recovery_codes WHERE user_id=$1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectCommit()
+
+	assert.NoError(t, DisableTwoFactor(sqlxDB, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseTOTPStep_Replay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	query := regexp.QuoteMeta(`UPDATE users SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1`)
+	mock.ExpectExec(query).WithArgs(int64(100), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(int64(100), 1).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ok, err := UseTOTPStep(sqlxDB, 1, 100)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = UseTOTPStep(sqlxDB, 1, 100)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE recovery_codes SET used_at=$1
+WHERE user_id=$2 AND code_hash=$3 AND used_at IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), 1, hashRecoveryCode("aaaaa-bbbbb")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ok, err := UseRecoveryCode(sqlxDB, 1, "AAAAA-BBBBB")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountRecoveryCodes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM recovery_codes WHERE user_id=$1 AND used_at IS NULL`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := CountRecoveryCodes(sqlxDB, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
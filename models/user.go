@@ -1,23 +1,111 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 )
 
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+var ErrDuplicateEmail = errors.New("an account with this email already exists")
+
 type User struct {
-	ID       int    `db:"id"`
-	Email    string `db:"email"`
-	Password string `db:"password"`
+	ID         int        `db:"id"`
+	Email      string     `db:"email"`
+	Password   string     `db:"password"`
+	VerifiedAt *time.Time `db:"verified_at"`
+	// TOTPSecret — секрет TOTP в base32; задан только при включённой двухфакторной аутентификации.
+	TOTPSecret    *string    `db:"totp_secret"`
+	TOTPEnabledAt *time.Time `db:"totp_enabled_at"`
+	Role          string     `db:"role"`
+	// DisabledAt — когда администратор отключил аккаунт; отключённый пользователь не может войти.
+	DisabledAt *time.Time `db:"disabled_at"`
+}
+
+// IsVerified сообщает, подтвердил ли пользователь адрес электронной почты.
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// IsAdmin сообщает, является ли пользователь администратором.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsDisabled сообщает, отключён ли аккаунт администратором.
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// TwoFactorEnabled сообщает, включена ли у пользователя двухфакторная аутентификация.
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != nil
 }
 
+// CreateUser сохраняет пользователя вместе с его блокнотом по умолчанию. Если адрес уже занят,
+// в том числе в другом регистре, возвращает ErrDuplicateEmail.
 func (u *User) CreateUser(db *sqlx.DB) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	query := `INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id`
-	return db.QueryRowx(query, u.Email, u.Password).Scan(&u.ID)
+	err = tx.QueryRowx(query, u.Email, u.Password).Scan(&u.ID)
+	if isUniqueViolation(err) {
+		return ErrDuplicateEmail
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := createDefaultNotebook(tx, u.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// EmailExists сообщает, зарегистрирован ли адрес, без учёта регистра.
+func EmailExists(db *sqlx.DB, email string) (bool, error) {
+	var exists bool
+	err := db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email)=LOWER($1))`, email)
+	return exists, err
+}
+
+// GetUserByEmail возвращает пользователя по адресу без учёта регистра, как его проверяет EmailExists.
+// Уникальность адреса без учёта регистра обеспечивает индекс users_email_lower_key.
 func GetUserByEmail(db *sqlx.DB, email string) (*User, error) {
 	var user User
-	query := `SELECT id, email, password FROM users WHERE email=$1`
+	query := `SELECT id, email, password, verified_at, totp_secret, totp_enabled_at, role, disabled_at FROM users
+WHERE LOWER(email)=LOWER($1)`
 	err := db.Get(&user, query, email)
 	return &user, err
 }
+
+func GetUserByID(db *sqlx.DB, id int) (*User, error) {
+	var user User
+	query := `SELECT id, email, password, verified_at, totp_secret, totp_enabled_at, role, disabled_at FROM users
+WHERE id=$1`
+
+	err := db.Get(&user, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateLastLogin запоминает время последнего входа пользователя.
+func UpdateLastLogin(db *sqlx.DB, userID int) error {
+	_, err := db.Exec(`UPDATE users SET last_login_at=$1 WHERE id=$2`, time.Now(), userID)
+	return err
+}
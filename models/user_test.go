@@ -1,11 +1,15 @@
 package models
 
 import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
-	"regexp"
-	"testing"
 )
 
 func TestUser_CreateUser(t *testing.T) {
@@ -24,12 +28,59 @@ func TestUser_CreateUser(t *testing.T) {
 
 	rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id`)).
 		WithArgs(user.Email, user.Password).
 		WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO notebooks (user_id, name, is_default) VALUES ($1, $2, TRUE)`)).
+		WithArgs(1, DefaultNotebookName).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = user.CreateUser(sqlxDB)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUser_CreateUser_DuplicateEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	// Адрес отличается от занятого только регистром.
+	user := &User{Email: "Test@Example.com", Password: "hashedpassword"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id`)).
+		WithArgs(user.Email, user.Password).
+		WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: "users_email_lower_key"})
+	mock.ExpectRollback()
 
 	err = user.CreateUser(sqlxDB)
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email)=LOWER($1))`)).
+		WithArgs("Test@Example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := EmailExists(sqlxDB, "Test@Example.com")
 	assert.NoError(t, err)
+	assert.True(t, exists)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -47,17 +98,87 @@ func TestGetUserByEmail(t *testing.T) {
 		ID:       1,
 		Email:    email,
 		Password: "hashedpassword",
+		Role:     RoleUser,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "email", "password"}).
-		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password)
+	rows := sqlmock.NewRows([]string{"id", "email", "password", "verified_at", "totp_secret", "totp_enabled_at",
+		"role", "disabled_at"}).
+		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, nil, nil, nil, RoleUser, nil)
 
-	mock.ExpectQuery(`SELECT id, email, password FROM users WHERE email=\$1`).
-		WithArgs(email).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, verified_at, totp_secret, totp_enabled_at, role, disabled_at
+FROM users WHERE LOWER(email)=LOWER($1)`)).
+		WithArgs("Test@Example.com").
 		WillReturnRows(rows)
 
-	user, err := GetUserByEmail(sqlxDB, email)
+	user, err := GetUserByEmail(sqlxDB, "Test@Example.com")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedUser, user)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetUserByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	verifiedAt := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "email", "password", "verified_at", "totp_secret", "totp_enabled_at",
+		"role", "disabled_at"}).
+		AddRow(1, "test@example.com", "hashedpassword", verifiedAt, nil, nil, RoleAdmin, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, verified_at, totp_secret, totp_enabled_at, role, disabled_at
+FROM users WHERE id=$1`)).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	user, err := GetUserByID(sqlxDB, 1)
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, "test@example.com", user.Email)
+		assert.True(t, user.IsVerified())
+		assert.True(t, user.IsAdmin())
+		assert.False(t, user.IsDisabled())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, verified_at, totp_secret, totp_enabled_at, role, disabled_at
+FROM users WHERE id=$1`)).
+		WithArgs(2).
+		WillReturnError(sql.ErrNoRows)
+
+	user, err := GetUserByID(sqlxDB, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateLastLogin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET last_login_at=$1 WHERE id=$2`)).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, UpdateLastLogin(sqlxDB, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
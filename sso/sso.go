@@ -0,0 +1,137 @@
+// Package sso реализует вход через внешний провайдер OpenID Connect по коду авторизации с PKCE.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const randomBytes = 32
+
+var (
+	ErrMissingIDToken = errors.New("sso: token response has no id_token")
+	ErrNonceMismatch  = errors.New("sso: id_token nonce does not match")
+)
+
+// Config — настройки провайдера, выданные при регистрации приложения.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL — адрес обработчика /login/oidc/callback, зарегистрированный у провайдера.
+	RedirectURL string
+	// Name — название провайдера на кнопке входа.
+	Name string
+}
+
+// Identity — пользователь, подтверждённый провайдером.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// AuthRequest — одноразовые параметры входа, которые хранятся в сессии до возврата пользователя от провайдера.
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// Provider выполняет вход через один провайдер OpenID Connect.
+type Provider struct {
+	Name     string
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// New загружает настройки провайдера из его документа discovery (/.well-known/openid-configuration).
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("sso: discover %s: %w", cfg.Issuer, err)
+	}
+
+	return &Provider{
+		Name: cfg.Name,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, randomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewAuthRequest создаёт state, nonce и code_verifier для нового входа.
+func NewAuthRequest() (AuthRequest, error) {
+	state, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+	return AuthRequest{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера.
+func (p *Provider) AuthCodeURL(req AuthRequest) string {
+	return p.oauth2.AuthCodeURL(req.State, oidc.Nonce(req.Nonce), oauth2.S256ChallengeOption(req.Verifier))
+}
+
+// Exchange обменивает код авторизации на токены и проверяет подпись, издателя, получателя,
+// срок действия и nonce id_token.
+func (p *Provider) Exchange(ctx context.Context, req AuthRequest, code string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(req.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("sso: exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("sso: verify id_token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(req.Nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"` //nolint:tagliatelle // имя claim задано OpenID Connect
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("sso: parse claims: %w", err)
+	}
+
+	return &Identity{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, nil
+}
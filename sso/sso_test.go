@@ -0,0 +1,228 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID = "notes"
+	testCode     = "auth-code"
+)
+
+// mockIdP — минимальный провайдер OpenID Connect: discovery, JWKS и обмен кода с проверкой PKCE.
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// challenge и nonce запоминаются из запроса авторизации.
+	challenge string
+	nonce     string
+	claims    map[string]any
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &mockIdP{t: t, key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		idp.t.Error(err)
+	}
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	issuer := idp.server.URL
+	idp.writeJSON(w, map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	idp.writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("code") != testCode {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	idp.writeJSON(w, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idp.signIDToken(),
+	})
+}
+
+func (idp *mockIdP) signIDToken() string {
+	claims := map[string]any{
+		"iss":   idp.server.URL,
+		"aud":   testClientID,
+		"sub":   "user-42",
+		"nonce": idp.nonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	require.NoError(idp.t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(idp.t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	require.NoError(idp.t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// authorize имитирует переход пользователя на страницу входа провайдера.
+func (idp *mockIdP) authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, idp.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	idp.challenge = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+	return query
+}
+
+func newTestProvider(t *testing.T, idp *mockIdP) *Provider {
+	t.Helper()
+
+	provider, err := New(context.Background(), Config{
+		Issuer:      idp.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://notes.test/login/oidc/callback",
+		Name:        "Company SSO",
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestProvider_Login(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.claims = map[string]any{"email": "alice@example.com", "email_verified": true}
+	provider := newTestProvider(t, idp)
+
+	req, err := NewAuthRequest()
+	require.NoError(t, err)
+
+	query := idp.authorize(t, provider.AuthCodeURL(req))
+	assert.Equal(t, req.State, query.Get("state"))
+	assert.Equal(t, testClientID, query.Get("client_id"))
+	assert.Contains(t, query.Get("scope"), "openid")
+
+	identity, err := provider.Exchange(context.Background(), req, testCode)
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{
+		Issuer:        idp.server.URL,
+		Subject:       "user-42",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+	}, identity)
+}
+
+func TestProvider_Exchange_WrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+
+	req, err := NewAuthRequest()
+	require.NoError(t, err)
+	idp.authorize(t, provider.AuthCodeURL(req))
+
+	other, err := NewAuthRequest()
+	require.NoError(t, err)
+	req.Verifier = other.Verifier
+
+	_, err = provider.Exchange(context.Background(), req, testCode)
+	assert.Error(t, err)
+}
+
+func TestProvider_Exchange_NonceMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+
+	req, err := NewAuthRequest()
+	require.NoError(t, err)
+	idp.authorize(t, provider.AuthCodeURL(req))
+	idp.nonce = "replayed"
+
+	_, err = provider.Exchange(context.Background(), req, testCode)
+	assert.ErrorIs(t, err, ErrNonceMismatch)
+}
+
+func TestProvider_Exchange_WrongAudience(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.claims = map[string]any{"aud": "another-app"}
+	provider := newTestProvider(t, idp)
+
+	req, err := NewAuthRequest()
+	require.NoError(t, err)
+	idp.authorize(t, provider.AuthCodeURL(req))
+
+	_, err = provider.Exchange(context.Background(), req, testCode)
+	assert.Error(t, err)
+}
+
+func TestNew_DiscoveryFails(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := New(context.Background(), Config{Issuer: server.URL, ClientID: testClientID})
+	assert.Error(t, err)
+}
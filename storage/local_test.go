@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_PutOpenDelete(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocal(root)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "notes/1/abc", strings.NewReader("data"), 4, "text/plain"))
+	_, err = os.Stat(filepath.Join(root, "notes", "1", "abc"))
+	assert.NoError(t, err)
+
+	rc, err := store.Open(ctx, "notes/1/abc")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "data", string(data))
+
+	require.NoError(t, store.Delete(ctx, "notes/1/abc"))
+	require.NoError(t, store.Delete(ctx, "notes/1/abc"))
+
+	_, err = store.Open(ctx, "notes/1/abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../etc/passwd", "/abs", "a//b", `a\b`} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "")
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
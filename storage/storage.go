@@ -0,0 +1,31 @@
+// Package storage хранит содержимое вложений заметок во внешнем хранилище.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Storage сохраняет и отдаёт объекты по ключу вида "notes/12/abc".
+// Удаление отсутствующего объекта не считается ошибкой.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validateKey не допускает абсолютных путей, ".." и пустых сегментов в ключах.
+func validateKey(key string) error {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	return nil
+}
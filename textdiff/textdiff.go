@@ -0,0 +1,175 @@
+package textdiff
+
+import "strings"
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// maxCells ограничивает размер таблицы LCS; для больших текстов
+// изменённая часть показывается целиком как удаление и вставка.
+const maxCells = 1 << 20
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Class возвращает CSS-класс строки диффа.
+func (l Line) Class() string {
+	switch l.Op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	case Equal:
+	}
+	return "equal"
+}
+
+// Prefix возвращает маркер строки в формате unified diff.
+func (l Line) Prefix() string {
+	switch l.Op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	case Equal:
+	}
+	return " "
+}
+
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Lines строит построчный дифф между oldText и newText.
+func Lines(oldText, newText string) []Line {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+	result = append(result, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+	return result
+}
+
+func diffMiddle(a, b []string) []Line {
+	if len(a)*len(b) > maxCells {
+		result := make([]Line, 0, len(a)+len(b))
+		for _, text := range a {
+			result = append(result, Line{Op: Delete, Text: text})
+		}
+		for _, text := range b {
+			result = append(result, Line{Op: Insert, Text: text})
+		}
+		return result
+	}
+
+	// lcs[i][j] — длина наибольшей общей подпоследовательности a[i:] и b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	result := make([]Line, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		result = append(result, Line{Op: Insert, Text: b[j]})
+	}
+	return result
+}
+
+// Changed сообщает, есть ли в диффе изменённые строки.
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge объединяет две версии текста построчно. Совпадающие строки остаются как есть,
+// а каждый расходящийся участок оформляется маркерами конфликта в стиле git:
+// сначала строки ours (под меткой oursLabel), затем строки theirs.
+// Второе значение сообщает, были ли расхождения.
+func Merge(ours, theirs, oursLabel, theirsLabel string) (string, bool) {
+	var out, left, right []string
+	conflict := false
+
+	flush := func() {
+		if len(left) == 0 && len(right) == 0 {
+			return
+		}
+		conflict = true
+		out = append(out, "<<<<<<< "+oursLabel)
+		out = append(out, left...)
+		out = append(out, "=======")
+		out = append(out, right...)
+		out = append(out, ">>>>>>> "+theirsLabel)
+		left, right = nil, nil
+	}
+
+	for _, line := range Lines(ours, theirs) {
+		switch line.Op {
+		case Delete:
+			left = append(left, line.Text)
+		case Insert:
+			right = append(right, line.Text)
+		case Equal:
+			flush()
+			out = append(out, line.Text)
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n"), conflict
+}
@@ -0,0 +1,80 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldText  string
+		newText  string
+		expected []Line
+	}{
+		{
+			name:     "identical",
+			oldText:  "a\nb",
+			newText:  "a\nb",
+			expected: []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			name:     "line changed",
+			oldText:  "a\nb\nc",
+			newText:  "a\nx\nc",
+			expected: []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "x"}, {Equal, "c"}},
+		},
+		{
+			name:     "line inserted",
+			oldText:  "a\nc",
+			newText:  "a\nb\nc",
+			expected: []Line{{Equal, "a"}, {Insert, "b"}, {Equal, "c"}},
+		},
+		{
+			name:     "from empty",
+			oldText:  "",
+			newText:  "a",
+			expected: []Line{{Insert, "a"}},
+		},
+		{
+			name:     "crlf is normalized",
+			oldText:  "a\r\nb",
+			newText:  "a\nb",
+			expected: []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Lines(tt.oldText, tt.newText))
+		})
+	}
+}
+
+func TestLines_LargeInputFallsBack(t *testing.T) {
+	oldText := strings.Repeat("old\n", 2000)
+	newText := strings.Repeat("new\n", 2000)
+
+	lines := Lines(oldText, newText)
+	assert.True(t, Changed(lines))
+	assert.Len(t, lines, 4001)
+}
+
+func TestLine_ClassAndPrefix(t *testing.T) {
+	assert.Equal(t, "insert", Line{Op: Insert}.Class())
+	assert.Equal(t, "-", Line{Op: Delete}.Prefix())
+	assert.Equal(t, " ", Line{Op: Equal}.Prefix())
+}
+
+func TestMerge(t *testing.T) {
+	merged, conflict := Merge("a\nb\nc", "a\nx\nc\nd", "current", "yours")
+	assert.True(t, conflict)
+	assert.Equal(t, "a\n<<<<<<< current\nb\n=======\nx\n>>>>>>> yours\nc\n<<<<<<< current\n=======\nd\n>>>>>>> yours",
+		merged)
+
+	merged, conflict = Merge("same\ntext", "same\r\ntext", "current", "yours")
+	assert.False(t, conflict)
+	assert.Equal(t, "same\ntext", merged)
+}
@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcKey — ключ из тестовых векторов RFC 4226 и RFC 6238.
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTP_RFC4226(t *testing.T) {
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range expected {
+		assert.Equal(t, code, hotp(rfcKey, uint64(counter), 6), "counter %d", counter)
+	}
+}
+
+func TestTOTP_RFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		step := Step(time.Unix(tt.unix, 0))
+		assert.Equal(t, tt.code, hotp(rfcKey, uint64(step), 8), "time %d", tt.unix)
+	}
+}
+
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString(rfcKey)
+
+	code, err := Code(secret, time.Unix(59, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	_, err = Code("not base32!", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// Соседние шаги принимаются, более далёкие — нет.
+	_, ok = Validate(secret, code, now.Add(Period))
+	assert.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(-Period))
+	assert.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(2*Period))
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, code[:3]+" "+code[3:], now)
+	assert.True(t, ok)
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+	_, ok = Validate("", code, now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	require.NoError(t, err)
+	b, err := GenerateSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 32)
+	assert.NotEqual(t, a, b)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Notes", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Notes:alice@example.com", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "Notes", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+	assert.Equal(t, "30", u.Query().Get("period"))
+}
@@ -0,0 +1,111 @@
+// Package validation проверяет данные форм: адреса электронной почты и пароли.
+package validation
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMinPasswordLength — минимальная длина пароля, если она не задана в настройках.
+	DefaultMinPasswordLength = 8
+	// maxPasswordBytes — bcrypt учитывает только первые 72 байта пароля.
+	maxPasswordBytes = 72
+	maxEmailLength   = 254
+)
+
+var (
+	ErrEmailRequired    = errors.New("email is required")
+	ErrEmailInvalid     = errors.New("enter a valid email address, like name@example.com")
+	ErrPasswordRequired = errors.New("password is required")
+	ErrPasswordTooLong  = fmt.Errorf("password must be at most %d bytes long", maxPasswordBytes)
+	ErrPasswordCommon   = errors.New("this password is too common, choose a less predictable one")
+	ErrPasswordIsEmail  = errors.New("password must not be the same as your email address")
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+func parseCommonPasswords(file string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(file, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[line] = struct{}{}
+	}
+	return passwords
+}
+
+// Errors — сообщения об ошибках формы по именам полей. Для каждого поля хранится первая найденная ошибка.
+type Errors map[string]string
+
+// Add запоминает ошибку поля, если для него ещё нет ошибки.
+func (e Errors) Add(field string, err error) {
+	if _, ok := e[field]; !ok {
+		e[field] = Message(err)
+	}
+}
+
+// Message возвращает текст ошибки для показа пользователю — с заглавной буквы.
+func Message(err error) string {
+	msg := err.Error()
+	if r, size := utf8.DecodeRuneInString(msg); size > 0 {
+		msg = string(unicode.ToUpper(r)) + msg[size:]
+	}
+	return msg
+}
+
+// Email проверяет адрес и возвращает его без окружающих пробелов.
+// Принимается только сам адрес, без имени и угловых скобок.
+func Email(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", ErrEmailRequired
+	}
+	if len(value) > maxEmailLength {
+		return "", ErrEmailInvalid
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name != "" || addr.Address != value {
+		return "", ErrEmailInvalid
+	}
+	if at := strings.LastIndexByte(value, '@'); !strings.Contains(value[at+1:], ".") {
+		return "", ErrEmailInvalid
+	}
+	return value, nil
+}
+
+// PasswordPolicy — требования к новому паролю.
+type PasswordPolicy struct {
+	// MinLength — минимальная длина в символах.
+	MinLength int
+}
+
+// Check проверяет новый пароль пользователя с адресом email.
+func (p PasswordPolicy) Check(password, email string) error {
+	switch {
+	case password == "":
+		return ErrPasswordRequired
+	case utf8.RuneCountInString(password) < p.MinLength:
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	case len(password) > maxPasswordBytes:
+		return ErrPasswordTooLong
+	case email != "" && strings.EqualFold(password, email):
+		return ErrPasswordIsEmail
+	}
+
+	if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+		return ErrPasswordCommon
+	}
+	return nil
+}
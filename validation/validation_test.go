@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      error
+	}{
+		{"alice@example.com", "alice@example.com", nil},
+		{"  alice@example.com ", "alice@example.com", nil},
+		{"", "", ErrEmailRequired},
+		{"   ", "", ErrEmailRequired},
+		{"alice", "", ErrEmailInvalid},
+		{"alice@localhost", "", ErrEmailInvalid},
+		{"Alice <alice@example.com>", "", ErrEmailInvalid},
+		{"alice@@example.com", "", ErrEmailInvalid},
+		{strings.Repeat("a", 250) + "@example.com", "", ErrEmailInvalid},
+	}
+	for _, tt := range tests {
+		email, err := Email(tt.input)
+		assert.Equal(t, tt.expected, email, "input %q", tt.input)
+		assert.ErrorIs(t, err, tt.err, "input %q", tt.input)
+	}
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10}
+
+	assert.NoError(t, policy.Check("correct horse battery", "alice@example.com"))
+	assert.ErrorIs(t, policy.Check("", "alice@example.com"), ErrPasswordRequired)
+	assert.EqualError(t, policy.Check("short", ""), "password must be at least 10 characters long")
+	assert.ErrorIs(t, policy.Check(strings.Repeat("x", 73), ""), ErrPasswordTooLong)
+	assert.ErrorIs(t, policy.Check("Alice@Example.com", "alice@example.com"), ErrPasswordIsEmail)
+	assert.ErrorIs(t, policy.Check("Password1234", ""), ErrPasswordCommon)
+	assert.ErrorIs(t, policy.Check("qwertyuiop", ""), ErrPasswordCommon)
+
+	// Длина считается в символах, а не в байтах.
+	assert.NoError(t, PasswordPolicy{MinLength: 8}.Check("пароль-ёжик", ""))
+}
+
+func TestCommonPasswords(t *testing.T) {
+	assert.Contains(t, commonPasswords, "123456")
+	assert.NotContains(t, commonPasswords, "")
+	for password := range commonPasswords {
+		assert.False(t, strings.HasPrefix(password, "#"))
+		assert.Equal(t, strings.ToLower(password), password)
+	}
+}
+
+func TestErrors_Add(t *testing.T) {
+	errs := Errors{}
+	errs.Add("email", ErrEmailInvalid)
+	errs.Add("email", ErrEmailRequired)
+	errs.Add("password", ErrPasswordRequired)
+
+	assert.Equal(t, Errors{
+		"email":    "Enter a valid email address, like name@example.com",
+		"password": "Password is required",
+	}, errs)
+}